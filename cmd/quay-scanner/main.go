@@ -2,28 +2,45 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	// Import the new config package
 	"quay-scanner/internal/config"
 	"quay-scanner/internal/formatter"
+	"quay-scanner/internal/notifier"
+	"quay-scanner/internal/policy"
+	"quay-scanner/internal/progress"
 	"quay-scanner/internal/quay"
+	"quay-scanner/internal/report"
+	"quay-scanner/internal/scanner"
 )
 
+// Exit codes. 0 is success, 1 is a runtime/setup error. exitPolicyViolation is
+// distinct so the tool can be used as a CI gate: a policy failure is not the
+// same kind of problem as a crash or a bad flag.
+const exitPolicyViolation = 2
+
 // --- Constants ---
 // const defaultWorkers = 5 // Keep this or move to config if desired
-const defaultConfigPath = "config/config.yaml" // Define default config path
+// defaultConfigPath is the -config flag's default. It's empty rather than a
+// fixed path so that, unless the operator overrides it, config.LoadDefault
+// searches the usual locations ($QUAYSCANNER_CONFIG, ./config.yaml, XDG,
+// /etc) instead of only ever looking in one place.
+const defaultConfigPath = ""
 
 // CliConfig holds configuration derived ONLY from flags and environment variables
 // Renamed from Config to avoid clash with AppConfig
@@ -35,6 +52,11 @@ type CliConfig struct {
 	Token        string
 	NumWorkers   int
 	ConfigFile   string // Add flag for custom config file path
+	Registry     string // Name of the registries entry to use; defaults to config.yaml's default_registry
+	Platforms    string // Comma-separated platform filter for manifest-list fan-out, e.g. "linux/amd64,linux/arm64"
+	NoProgress   bool   // Disable the stderr progress bar even on an interactive terminal
+	SummaryOnly  bool   // Suppress per-image detail, printing only the aggregate summary
+	TopPackages  int    // Number of most-affected packages to include in the summary
 }
 
 // --- Main Execution Flow ---
@@ -51,17 +73,44 @@ func main() {
 	// 2. Setup logging based on CLI configuration
 	setupLogging(cliCfg.Verbose)
 
-	// 3. Load application configuration from file
-	appCfg, err := config.LoadConfig(cliCfg.ConfigFile) // Use path from flag
+	// 3. Load application configuration from file. An explicit -config flag
+	// loads that exact path; otherwise LoadDefault searches the usual
+	// locations (env var, ./config.yaml, XDG, /etc).
+	var appCfg config.AppConfig
+	if cliCfg.ConfigFile != "" {
+		appCfg, err = config.LoadConfig(cliCfg.ConfigFile)
+	} else {
+		appCfg, err = config.LoadDefault()
+	}
 	if err != nil {
-		// LoadConfig now only returns error on read/parse failure
-		// File not found is handled internally with defaults/warnings
-		fmt.Fprintf(os.Stderr, "Error processing configuration file '%s': %v\n", cliCfg.ConfigFile, err)
+		// LoadConfig/LoadDefault only return an error on read/parse failure.
+		// File not found is handled internally with defaults/warnings.
+		if cliCfg.ConfigFile != "" {
+			fmt.Fprintf(os.Stderr, "Error processing configuration file '%s': %v\n", cliCfg.ConfigFile, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error processing configuration: %v\n", err)
+		}
 		os.Exit(1) // Exit if config file is present but invalid
 	}
-	log.Printf("INFO: Using Quay API Base URL: %s", appCfg.Quay.APIBaseURL)
-	log.Printf("INFO: Using HTTP Timeout: %v", appCfg.Quay.GetTimeout())
-	log.Printf("INFO: Using User-Agent: %s", appCfg.Quay.UserAgent)
+	defaultRegistry := cliCfg.Registry
+	if defaultRegistry == "" {
+		defaultRegistry = appCfg.DefaultRegistry
+	}
+	// defaultHost is the registry's own hostname (parsed from its
+	// api_base_url), not the possibly-arbitrary registries: key above — the
+	// scanner.Registry built by buildScannerRegistry dispatches by host, the
+	// same way parseImageURL resolves one from a host-qualified image
+	// reference, so a host-less ref has to resolve to that same host.
+	var defaultHost string
+	if regCfg, ok := appCfg.Registry(defaultRegistry); ok {
+		defaultHost = registryHost(regCfg.APIBaseURL)
+		log.Printf("INFO: Using registry %q, API Base URL: %s", defaultRegistry, regCfg.APIBaseURL)
+		log.Printf("INFO: Using HTTP Timeout: %v", regCfg.GetTimeout())
+		log.Printf("INFO: Using User-Agent: %s", regCfg.UserAgent)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: registry %q is not configured (see registries: in config.yaml or -registry flag)\n", defaultRegistry)
+		os.Exit(1)
+	}
 
 	// 4. Load the list of image URLs to process (using CLI config)
 	imageURLs, err := loadImageURLs(cliCfg) // Pass CLI config
@@ -75,31 +124,91 @@ func main() {
 	}
 	log.Printf("INFO: Preparing to process %d image(s).\n", len(imageURLs))
 
-	// 5. Create the Quay API client using merged config (App Cfg + CLI Cfg)
-	quayClient, err := quay.NewClient(
-		appCfg.Quay.APIBaseURL,   // From config file
-		cliCfg.Token,             // From flag/env
-		appCfg.Quay.GetTimeout(), // From config file
-		appCfg.Quay.UserAgent,    // From config file
-	)
+	// 5. Build the scanner registry: one Quay client per config.yaml
+	// `registries` entry, plus any additional registries declared in
+	// config.yaml's `backends`.
+	scannerRegistry, quayClients, err := buildScannerRegistry(appCfg, cliCfg.Token)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating Quay client: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error creating scanner backends: %v\n", err)
 		os.Exit(1)
 	}
 
-	// 6. Run the worker pool to process images concurrently
+	// Watch config.yaml for SIGHUP-triggered reloads so a long scan (or a
+	// daemon/sidecar driving repeated ones) picks up registry base URL,
+	// timeout, retry, and credential changes without restarting. A failed
+	// run-time config file has no bearing on the scan already under way, so
+	// this only affects requests made after a successful reload.
+	watcherCtx, stopWatcher := context.WithCancel(context.Background())
+	defer stopWatcher()
+	configWatcher := config.NewWatcher(config.ResolveConfigPath(cliCfg.ConfigFile), appCfg)
+	configWatcher.Subscribe(func(old, new config.AppConfig) {
+		for name, client := range quayClients {
+			regCfg, ok := new.Registry(name)
+			if !ok {
+				continue
+			}
+			if err := client.UpdateConfig(regCfg.APIBaseURL, registryCredentials(regCfg, cliCfg.Token), regCfg.GetTimeout(), regCfg.UserAgent, registryRetryPolicy(regCfg)); err != nil {
+				log.Printf("Warning: could not apply reloaded config to registry %q: %v", name, err)
+			}
+		}
+	})
+	go configWatcher.Start(watcherCtx)
+
+	// 6. Run the worker pool to process images concurrently, streaming
+	// completed scans to any configured webhooks (and, for -format ndjson,
+	// directly to stdout) as they land.
+	scanNotifier := notifier.New(appCfg.Notify)
 	log.Printf("INFO: Starting vulnerability scan with %d workers...\n", cliCfg.NumWorkers)
-	results := runWorkerPool(imageURLs, quayClient, cliCfg.NumWorkers)
+	platforms := platformSet(cliCfg.Platforms, appCfg.Platforms)
+	streamNDJSON := cliCfg.OutputFormat == "ndjson"
+	var onResult func(quay.ImageScanResult)
+	if streamNDJSON {
+		onResult = func(result quay.ImageScanResult) {
+			if err := formatter.WriteNDJSONImage(os.Stdout, result); err != nil {
+				fmt.Fprintf(os.Stderr, "Error streaming NDJSON record: %v\n", err)
+			}
+		}
+	}
+	renderProgress := progress.ShouldRender(os.Stderr.Fd(), cliCfg.Verbose, cliCfg.NoProgress)
+	bar := progress.New(os.Stderr, len(imageURLs), renderProgress)
+	startTime := time.Now()
+	results := runWorkerPool(imageURLs, scannerRegistry, defaultHost, cliCfg.NumWorkers, scanNotifier, platforms, onResult, bar)
 	log.Println("INFO: Vulnerability scan finished.")
 
-	// 7. Format and output the results
+	// 7. Evaluate the configured policy (if any) against each scan result.
+	verdicts := policy.EvaluateAll(appCfg.Policy, results)
+	if appCfg.Policy.Enabled() {
+		log.Printf("INFO: Evaluated policy (fail_on=%s) against %d image(s).\n", appCfg.Policy.FailOn, len(verdicts))
+	}
+
+	// Build the cross-image summary once; it feeds the webhook notifier and
+	// every output format.
+	summary := report.Summarize(results, cliCfg.TopPackages)
+
+	// Emit a final summary event once every image has been scanned, then wait
+	// for it (and any still-in-flight per-image events) to finish delivering
+	// before the process exits.
+	scanNotifier.NotifySummary(summary)
+	scanNotifier.Wait()
+
+	// 8. Format and output the results. NDJSON's per-image records were
+	// already streamed in step 6; only its terminal summary record remains.
 	log.Printf("INFO: Formatting output as %s...\n", cliCfg.OutputFormat)
-	err = outputResults(results, cliCfg.OutputFormat, os.Stdout)
+	if streamNDJSON {
+		err = formatter.WriteNDJSONSummary(os.Stdout, summary, time.Since(startTime))
+	} else {
+		err = outputResults(results, verdicts, summary, cliCfg.OutputFormat, cliCfg.SummaryOnly, appCfg.Policy.Enabled(), os.Stdout)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
 		os.Exit(1)
 	}
 
+	if policy.AnyFailed(verdicts) {
+		log.Println("INFO: One or more images failed the configured policy.")
+		os.Exit(exitPolicyViolation)
+	}
+
 	log.Println("INFO: Done.")
 }
 
@@ -112,11 +221,16 @@ func parseFlags() (CliConfig, error) {
 	// Define flags
 	flag.StringVar(&cfg.ImageURL, "image", "", "Single Quay.io image URL (mutually exclusive with -file)")
 	flag.StringVar(&cfg.InputFile, "file", "", "Path to JSON or YAML file containing a list of image URLs (mutually exclusive with -image)")
-	flag.StringVar(&cfg.OutputFormat, "format", "human", "Output format: 'json' or 'human'")
+	flag.StringVar(&cfg.OutputFormat, "format", "human", "Output format: 'json', 'human', or 'ndjson'")
 	flag.BoolVar(&cfg.Verbose, "verbose", false, "Enable verbose logging")
 	flag.StringVar(&cfg.Token, "token", "", "Quay API Bearer Token (optional, overrides QUAY_TOKEN env var)")
-	flag.IntVar(&cfg.NumWorkers, "workers", 5, "Number of concurrent workers (default: 5)")                                  // Set default here
-	flag.StringVar(&cfg.ConfigFile, "config", defaultConfigPath, "Path to the application configuration file (config.yaml)") // Add config flag
+	flag.IntVar(&cfg.NumWorkers, "workers", 5, "Number of concurrent workers (default: 5)")                                                                                                                               // Set default here
+	flag.StringVar(&cfg.ConfigFile, "config", defaultConfigPath, "Path to the application configuration file; if unset, searches $QUAYSCANNER_CONFIG, ./config.yaml, XDG config dir, then /etc/quay-scanner/config.yaml") // Add config flag
+	flag.StringVar(&cfg.Registry, "registry", "", "Name of the registries: entry in config.yaml to use for bare (no-host) image references (default: config.yaml's default_registry)")
+	flag.StringVar(&cfg.Platforms, "platforms", "", "Comma-separated platforms to scan for manifest-list images, e.g. 'linux/amd64,linux/arm64' (default: all)")
+	flag.BoolVar(&cfg.NoProgress, "no-progress", false, "Disable the stderr progress bar even when attached to a terminal")
+	flag.BoolVar(&cfg.SummaryOnly, "summary-only", false, "Suppress per-image detail and print only the aggregate summary")
+	flag.IntVar(&cfg.TopPackages, "top-packages", 5, "Number of most-affected packages to include in the summary")
 
 	// Custom usage message (update if needed)
 	flag.Usage = func() {
@@ -128,7 +242,7 @@ func parseFlags() (CliConfig, error) {
 		fmt.Fprintf(os.Stderr, "  Input file format (JSON): {\"images\": [\"quay.io/ns/repo:tag\", ...]}}\n")
 		fmt.Fprintf(os.Stderr, "  Input file format (YAML): images:\n    - quay.io/ns/repo:tag\n    - ...\n\n")
 		fmt.Fprintf(os.Stderr, "Configuration:\n")
-		fmt.Fprintf(os.Stderr, "  Uses settings from the file specified by -config (default: %s).\n", defaultConfigPath)
+		fmt.Fprintf(os.Stderr, "  Uses settings from the file specified by -config, or the default search path if unset.\n")
 		fmt.Fprintf(os.Stderr, "Authentication:\n")
 		fmt.Fprintf(os.Stderr, "  Uses QUAY_TOKEN environment variable or -token flag.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
@@ -145,8 +259,8 @@ func parseFlags() (CliConfig, error) {
 	if cfg.ImageURL != "" && cfg.InputFile != "" {
 		return cfg, fmt.Errorf("-image and -file flags are mutually exclusive")
 	}
-	if cfg.OutputFormat != "json" && cfg.OutputFormat != "human" {
-		return cfg, fmt.Errorf("invalid -format value '%s'. Must be 'json' or 'human'", cfg.OutputFormat)
+	if cfg.OutputFormat != "json" && cfg.OutputFormat != "human" && cfg.OutputFormat != "ndjson" {
+		return cfg, fmt.Errorf("invalid -format value '%s'. Must be 'json', 'human', or 'ndjson'", cfg.OutputFormat)
 	}
 	if cfg.NumWorkers <= 0 {
 		return cfg, fmt.Errorf("-workers must be a positive number")
@@ -225,9 +339,11 @@ func loadImageURLs(cliCfg CliConfig) ([]string, error) {
 	return inputList.Images, nil
 }
 
-// runWorkerPool remains the same conceptually
-func runWorkerPool(imageURLs []string, quayClient *quay.Client, numWorkers int) map[string]quay.ImageScanResult {
-	// ... (implementation is unchanged) ...
+// runWorkerPool fans imageURLs out across numWorkers goroutines and collects
+// their results into a map. Each result is also streamed to scanNotifier,
+// and to onResult (if non-nil, e.g. the NDJSON writer), as soon as it
+// arrives, so consumers see results before the whole batch finishes.
+func runWorkerPool(imageURLs []string, registry *scanner.Registry, defaultHost string, numWorkers int, scanNotifier *notifier.Notifier, platforms map[string]bool, onResult func(quay.ImageScanResult), bar *progress.Bar) map[string]quay.ImageScanResult {
 	numJobs := len(imageURLs)
 	jobs := make(chan string, numJobs)
 	results := make(chan quay.ImageScanResult, numJobs)
@@ -237,7 +353,7 @@ func runWorkerPool(imageURLs []string, quayClient *quay.Client, numWorkers int)
 	log.Printf("INFO: Starting %d workers...\n", numWorkers)
 	for w := 1; w <= numWorkers; w++ {
 		wg.Add(1)
-		go worker(w, quayClient, jobs, results, &wg)
+		go worker(w, registry, defaultHost, jobs, results, &wg, platforms, bar)
 	}
 
 	log.Println("INFO: Sending jobs to workers...")
@@ -255,6 +371,10 @@ func runWorkerPool(imageURLs []string, quayClient *quay.Client, numWorkers int)
 		for i := 0; i < numJobs; i++ {
 			result := <-results
 			allResults[result.ImageURL] = result
+			scanNotifier.NotifyScan(result)
+			if onResult != nil {
+				onResult(result)
+			}
 		}
 		log.Println("INFO: All results collected.")
 	}()
@@ -265,46 +385,80 @@ func runWorkerPool(imageURLs []string, quayClient *quay.Client, numWorkers int)
 
 	collectWg.Wait()
 	close(results)
+	bar.Stop()
 
 	return allResults
 }
 
-// worker remains the same
-func worker(id int, quayClient *quay.Client, jobs <-chan string, results chan<- quay.ImageScanResult, wg *sync.WaitGroup) {
-	// ... (implementation is unchanged) ...
+// worker pulls image references off jobs, dispatches each to the registered
+// Scanner for its registry, and pushes the result onto results.
+func worker(id int, registry *scanner.Registry, defaultHost string, jobs <-chan string, results chan<- quay.ImageScanResult, wg *sync.WaitGroup, platforms map[string]bool, bar *progress.Bar) {
 	defer wg.Done()
 	for imageURL := range jobs {
 		log.Printf("INFO: [Worker %d] Processing image: %s\n", id, imageURL)
-		result := processImage(imageURL, quayClient)
+		bar.Started(imageURL)
+		result := processImage(imageURL, registry, defaultHost, platforms)
 		results <- result
+		bar.Finished(result)
 		log.Printf("INFO: [Worker %d] Finished image: %s (Error: %t)\n", id, imageURL, result.Error != "")
 	}
 	log.Printf("INFO: [Worker %d] Exiting.\n", id)
 }
 
-// processImage remains the same
-func processImage(imageURL string, quayClient *quay.Client) quay.ImageScanResult {
-	// ... (implementation is unchanged) ...
+// processImage resolves imageURL to a registry/repo/tag, looks up the
+// Scanner registered for that registry, and runs the two-step scan. If the
+// backend supports ManifestInspector and the tag resolves to a manifest
+// list, the scan fans out across platforms instead (see scanManifestList).
+func processImage(imageURL string, registry *scanner.Registry, defaultHost string, platforms map[string]bool) quay.ImageScanResult {
 	result := quay.ImageScanResult{ImageURL: imageURL}
 
-	repo, tag, err := parseImageURL(imageURL)
+	host, repo, tag, err := parseImageURL(imageURL, defaultHost)
 	if err != nil {
 		result.Error = fmt.Sprintf("Parsing URL failed: %v", err)
 		return result
 	}
 
-	imageID, err := quayClient.GetImageID(repo, tag)
+	backend, err := registry.For(host)
 	if err != nil {
-		result.Error = fmt.Sprintf("Getting image ID failed: %v", err)
+		result.Error = err.Error()
 		return result
 	}
+
+	var imageID string
+	if mi, ok := backend.(scanner.ManifestInspector); ok {
+		tagDetail, err := mi.GetTagDetail(repo, tag)
+		if err != nil {
+			result.Error = fmt.Sprintf("Getting tag detail failed: %v", err)
+			return result
+		}
+		result.StartTs = tagDetail.StartTs
+		if tagDetail.IsManifestList {
+			return scanManifestList(imageURL, repo, tagDetail, mi, backend, platforms)
+		}
+		// Resolve the digest from the tagDetail already fetched above instead
+		// of calling backend.GetImageID, which would hit the same tag
+		// endpoint a second time.
+		imageID, err = quay.ImageIDFromTagDetail(tagDetail, tag)
+		if err != nil {
+			result.Error = fmt.Sprintf("Getting image ID failed: %v", err)
+			return result
+		}
+	} else {
+		var err error
+		imageID, err = backend.GetImageID(repo, tag)
+		if err != nil {
+			result.Error = fmt.Sprintf("Getting image ID failed: %v", err)
+			return result
+		}
+	}
 	if imageID == "" {
-		// This case should be handled by GetImageID returning an error now
+		// This case should be handled by GetImageID/ImageIDFromTagDetail
+		// returning an error now
 		result.Error = fmt.Sprintf("Could not find image ID for tag '%s' (tag might not exist or image details missing)", tag)
 		return result
 	}
 
-	report, err := quayClient.GetVulnerabilities(repo, imageID)
+	report, err := backend.GetVulnerabilities(repo, imageID)
 	if err != nil {
 		result.Error = fmt.Sprintf("Getting vulnerabilities failed: %v", err)
 		if report != nil {
@@ -317,39 +471,250 @@ func processImage(imageURL string, quayClient *quay.Client) quay.ImageScanResult
 	return result
 }
 
-// parseImageURL remains the same
-func parseImageURL(imageURL string) (repo string, tag string, err error) {
-	// ... (implementation is unchanged) ...
-	if !strings.HasPrefix(imageURL, "quay.io/") {
-		err = fmt.Errorf("image URL must start with 'quay.io/'")
+// scanManifestList fans a manifest-list image out into one scan per child
+// platform, running them concurrently and nesting the results under the
+// parent's Children map keyed by platform (e.g. "linux/amd64"). platforms,
+// if non-empty, restricts which child platforms are actually scanned.
+func scanManifestList(imageURL, repo string, tagDetail *quay.TagDetail, mi scanner.ManifestInspector, backend scanner.Scanner, platforms map[string]bool) quay.ImageScanResult {
+	result := quay.ImageScanResult{ImageURL: imageURL, StartTs: tagDetail.StartTs}
+
+	parentDigest := strings.TrimPrefix(tagDetail.ManifestDigest, "sha256:")
+	children, err := mi.GetManifestChildren(repo, parentDigest)
+	if err != nil {
+		result.Error = fmt.Sprintf("Getting manifest children failed: %v", err)
+		return result
+	}
+
+	result.Children = make(map[string]*quay.ImageScanResult, len(children))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for platform, digest := range children {
+		if len(platforms) > 0 && !platforms[platform] {
+			continue
+		}
+		wg.Add(1)
+		go func(platform, digest string) {
+			defer wg.Done()
+			childResult := &quay.ImageScanResult{ImageURL: fmt.Sprintf("%s (%s)", imageURL, platform)}
+			report, err := backend.GetVulnerabilities(repo, digest)
+			if err != nil {
+				childResult.Error = fmt.Sprintf("Getting vulnerabilities failed: %v", err)
+			} else {
+				childResult.Report = report
+			}
+			mu.Lock()
+			result.Children[platform] = childResult
+			mu.Unlock()
+		}(platform, digest)
+	}
+	wg.Wait()
+
+	if len(result.Children) == 0 {
+		result.Error = "manifest list had no child platforms matching the configured --platforms filter"
+	}
+	return result
+}
+
+// parseImageURL parses a standard image reference of the form
+// registry[:port]/repo[:tag|@digest] into its registry host, repository
+// path, and tag. A reference with no registry component (no dot/colon/port
+// before the first slash, matching Docker's own heuristic) is assumed to
+// target defaultHost. "quay.io/ns/repo:tag" references are host-qualified
+// and resolve to the quay.io host.
+func parseImageURL(imageURL string, defaultHost string) (host string, repo string, tag string, err error) {
+	remainder := imageURL
+	firstSlash := strings.Index(remainder, "/")
+	if firstSlash == -1 {
+		err = fmt.Errorf("invalid image URL format. Expected 'registry/repository/name:tag', got '%s'", imageURL)
 		return
 	}
-	trimmedURL := strings.TrimPrefix(imageURL, "quay.io/")
-	parts := strings.SplitN(trimmedURL, ":", 2)
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		err = fmt.Errorf("invalid image URL format. Expected 'quay.io/repository/name:tag', got '%s'", imageURL)
+
+	candidateHost := remainder[:firstSlash]
+	if looksLikeRegistryHost(candidateHost) {
+		host = candidateHost
+		remainder = remainder[firstSlash+1:]
+	} else {
+		host = defaultHost
+	}
+
+	if strings.Contains(remainder, "@") {
+		parts := strings.SplitN(remainder, "@", 2)
+		repo, tag = parts[0], parts[1]
+	} else {
+		parts := strings.SplitN(remainder, ":", 2)
+		if len(parts) != 2 {
+			err = fmt.Errorf("invalid image URL format. Expected 'repository/name:tag' or 'repository/name@digest', got '%s'", imageURL)
+			return
+		}
+		repo, tag = parts[0], parts[1]
+	}
+
+	if repo == "" || tag == "" {
+		err = fmt.Errorf("invalid image URL format. Expected 'repository/name:tag', got '%s'", imageURL)
 		return
 	}
-	repo = parts[0]
-	tag = parts[1]
 	if strings.Contains(repo, "..") || strings.Contains(tag, "..") || strings.Contains(tag, "/") {
 		err = fmt.Errorf("invalid characters in repository or tag")
 		return
 	}
-	return repo, tag, nil
+	return host, repo, tag, nil
+}
+
+// registryHost extracts the hostname (and port, if any) a registry's
+// api_base_url resolves to, e.g. "https://quay.corp.example/api/v1/" ->
+// "quay.corp.example". Returns "" if apiBaseURL doesn't parse to a URL with
+// a host, which buildScannerRegistry treats as a configuration error.
+func registryHost(apiBaseURL string) string {
+	u, err := url.Parse(apiBaseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// looksLikeRegistryHost reports whether s resembles a registry hostname
+// (contains a '.' or ':', or is exactly "localhost") rather than the first
+// path segment of a Docker Hub style repository, mirroring the heuristic
+// `docker pull` itself uses to split references.
+func looksLikeRegistryHost(s string) bool {
+	return s == "localhost" || strings.ContainsAny(s, ".:")
+}
+
+// buildScannerRegistry wires up a scanner.Registry: every entry in
+// appCfg.Registries (e.g. "quay.io", a private Quay Enterprise host) is
+// served by a quay.Client built from that registry's own credentials, and
+// any additional entries in appCfg.Backends register either a quay.Client
+// (type "quay") or an OCIScanner talking to a Clair v4 or Trivy-server
+// endpoint (types "clairv4", "trivy-server"). token, if non-empty, overrides
+// every registry's configured bearer token (the -token flag / QUAY_TOKEN).
+// It also returns the quay.Client built for each appCfg.Registries entry,
+// keyed by registry name, so a config.Watcher reload can push updates into
+// them via UpdateConfig.
+func buildScannerRegistry(appCfg config.AppConfig, token string) (*scanner.Registry, map[string]*quay.Client, error) {
+	scanners := make(map[string]scanner.Scanner, len(appCfg.Registries)+len(appCfg.Backends))
+	quayClients := make(map[string]*quay.Client, len(appCfg.Registries))
+
+	for name, regCfg := range appCfg.Registries {
+		client, err := quay.NewClient(regCfg.APIBaseURL, registryCredentials(regCfg, token), regCfg.GetTimeout(), regCfg.UserAgent, registryRetryPolicy(regCfg))
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating Quay client for registry %q: %w", name, err)
+		}
+		// scanner.Registry dispatches by the host parsed from an image
+		// reference (see parseImageURL), not by the operator-chosen
+		// registries: key, so a host-qualified reference to a private Quay
+		// Enterprise deployment resolves to this client regardless of what
+		// name it was given in config.yaml.
+		host := registryHost(regCfg.APIBaseURL)
+		if host == "" {
+			return nil, nil, fmt.Errorf("registry %q has an invalid api_base_url %q", name, regCfg.APIBaseURL)
+		}
+		scanners[host] = client
+		quayClients[name] = client
+	}
+
+	defaultUserAgent := appCfg.Registries[appCfg.DefaultRegistry].UserAgent
+	defaultRetry := registryRetryPolicy(appCfg.Registries[appCfg.DefaultRegistry])
+
+	for host, backendCfg := range appCfg.Backends {
+		timeout := time.Duration(backendCfg.TimeoutSeconds) * time.Second
+		switch backendCfg.Type {
+		case "quay":
+			client, err := quay.NewClient(backendCfg.RegistryBaseURL, quay.Credentials{BearerToken: token}, timeout, defaultUserAgent, defaultRetry)
+			if err != nil {
+				return nil, nil, fmt.Errorf("creating Quay-compatible client for %q: %w", host, err)
+			}
+			scanners[host] = client
+		case "clairv4":
+			ociScanner, err := scanner.NewOCIScanner(backendCfg.RegistryBaseURL, backendCfg.ReportBaseURL, scanner.ReportBackendClairV4, token, timeout)
+			if err != nil {
+				return nil, nil, fmt.Errorf("creating Clair v4 scanner for %q: %w", host, err)
+			}
+			scanners[host] = ociScanner
+		case "trivy-server":
+			ociScanner, err := scanner.NewOCIScanner(backendCfg.RegistryBaseURL, backendCfg.ReportBaseURL, scanner.ReportBackendTrivyServer, token, timeout)
+			if err != nil {
+				return nil, nil, fmt.Errorf("creating Trivy-server scanner for %q: %w", host, err)
+			}
+			scanners[host] = ociScanner
+		default:
+			return nil, nil, fmt.Errorf("unknown backend type %q for registry %q", backendCfg.Type, host)
+		}
+	}
+
+	return scanner.NewRegistry(scanners, nil), quayClients, nil
+}
+
+// registryCredentials builds the quay.Credentials for regCfg, letting an
+// explicit CLI/env token (cliToken) override its configured BearerToken.
+func registryCredentials(regCfg config.RegistryConfig, cliToken string) quay.Credentials {
+	creds := quay.Credentials{
+		BearerToken: regCfg.BearerToken,
+		OAuthToken:  regCfg.OAuthToken,
+	}
+	if regCfg.BasicAuth != nil {
+		creds.BasicAuthUser = regCfg.BasicAuth.Username
+		creds.BasicAuthPass = regCfg.BasicAuth.Password
+	}
+	if cliToken != "" {
+		creds.BearerToken = cliToken
+	}
+	return creds
+}
+
+// registryRetryPolicy translates regCfg's retry settings (falling back to
+// config.DefaultRetryConfig() if unset, via GetRetry) into the quay
+// package's own decoupled RetryPolicy type.
+func registryRetryPolicy(regCfg config.RegistryConfig) quay.RetryPolicy {
+	retryCfg := regCfg.GetRetry()
+	retryOn := make(map[int]bool, len(retryCfg.RetryOn))
+	for _, code := range retryCfg.RetryOn {
+		retryOn[code] = true
+	}
+	return quay.RetryPolicy{
+		MaxAttempts:          retryCfg.MaxAttempts,
+		InitialBackoff:       time.Duration(retryCfg.InitialBackoff),
+		MaxBackoff:           time.Duration(retryCfg.MaxBackoff),
+		Multiplier:           retryCfg.Multiplier,
+		RetryOn:              retryOn,
+		RetryOnNetworkErrors: retryCfg.RetryOnNetworkErrors,
+	}
+}
+
+// platformSet builds the platform filter used by manifest-list fan-out: the
+// -platforms flag takes precedence over config.yaml's `platforms` default,
+// and an empty result means "scan every child platform".
+func platformSet(flagValue string, configDefault []string) map[string]bool {
+	var entries []string
+	if flagValue != "" {
+		entries = strings.Split(flagValue, ",")
+	} else {
+		entries = configDefault
+	}
+
+	set := make(map[string]bool, len(entries))
+	for _, p := range entries {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			set[p] = true
+		}
+	}
+	return set
 }
 
-// outputResults remains the same
-func outputResults(results map[string]quay.ImageScanResult, format string, writer io.Writer) error {
-	// ... (implementation is unchanged) ...
+// outputResults formats results (policy verdicts and the cross-image
+// summary alongside them) to writer. summaryOnly suppresses per-image
+// detail, leaving just the aggregate summary. policyEnabled gates whether
+// verdicts are rendered at all, since EvaluateAll always returns a Pass verdict
+// per image even when no `policy:` block is configured.
+func outputResults(results map[string]quay.ImageScanResult, verdicts map[string]policy.PolicyVerdict, summary report.Summary, format string, summaryOnly, policyEnabled bool, writer io.Writer) error {
 	switch format {
 	case "json":
-		err := formatter.FormatJSON(writer, results)
+		err := formatter.FormatJSON(writer, results, verdicts, summary, summaryOnly, policyEnabled)
 		if err != nil {
 			return fmt.Errorf("formatting JSON output: %w", err)
 		}
 	case "human":
-		formatter.FormatHumanReadable(writer, results)
+		formatter.FormatHumanReadable(writer, results, verdicts, summary, summaryOnly, policyEnabled)
 	default:
 		return fmt.Errorf("internal error: unknown output format '%s'", format)
 	}
@@ -5,42 +5,201 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/caarlos0/env/v6"
 	"gopkg.in/yaml.v3"
+
+	"quay-scanner/internal/notifier"
+	"quay-scanner/internal/policy"
 )
 
-// QuayConfig holds settings specific to the Quay client
-type QuayConfig struct {
-	APIBaseURL     string `yaml:"api_base_url"`
-	TimeoutSeconds int    `yaml:"timeout_seconds"` // Load as int, convert to duration later
-	UserAgent      string `yaml:"user_agent"`
+// envConfigPath, if set, names the config file LoadDefault should use
+// instead of searching the usual locations.
+const envConfigPath = "QUAYSCANNER_CONFIG"
+
+// defaultRegistryName is the key under AppConfig.Registries (and the
+// DefaultRegistry value) used when config.yaml doesn't define any
+// registries at all, preserving the old single-quay.io behavior.
+const defaultRegistryName = "quay.io"
+
+// Duration wraps time.Duration so it can be written in config.yaml as a Go
+// duration string (e.g. "750ms", "2m30s") instead of a bare integer of
+// seconds, via UnmarshalYAML/MarshalYAML delegating to time.ParseDuration.
+type Duration time.Duration
+
+// UnmarshalYAML decodes a YAML scalar like "15s" into a Duration.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML encodes a Duration back to its Go duration string form.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// RetryConfig controls how a registry's HTTP client retries failed
+// requests: up to MaxAttempts tries, waiting InitialBackoff and multiplying
+// by Multiplier each time (capped at MaxBackoff), for any status in RetryOn
+// or (if RetryOnNetworkErrors) a network-level error.
+type RetryConfig struct {
+	MaxAttempts          int      `yaml:"max_attempts"`
+	InitialBackoff       Duration `yaml:"initial_backoff"`
+	MaxBackoff           Duration `yaml:"max_backoff"`
+	Multiplier           float64  `yaml:"multiplier"`
+	RetryOn              []int    `yaml:"retry_on"`
+	RetryOnNetworkErrors bool     `yaml:"retry_on_network_errors"`
+}
+
+// DefaultRetryConfig returns the sane-defaults retry policy: 3 attempts,
+// 500ms backoff doubling up to 5s, retrying the status codes a transient
+// registry or gateway hiccup typically returns.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:          3,
+		InitialBackoff:       Duration(500 * time.Millisecond),
+		MaxBackoff:           Duration(5 * time.Second),
+		Multiplier:           2.0,
+		RetryOn:              []int{429, 502, 503, 504},
+		RetryOnNetworkErrors: true,
+	}
+}
+
+// BasicAuthConfig holds HTTP Basic auth credentials for a registry.
+type BasicAuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// RegistryConfig holds the settings and credentials for one named Quay (or
+// Quay-compatible) registry. BearerToken, OAuthToken, and BasicAuth's
+// fields support ${ENV_VAR} expansion on load, so secrets don't have to be
+// baked into config.yaml. At most one of BearerToken/OAuthToken/BasicAuth
+// should be set; quay.Client tries them in that order.
+type RegistryConfig struct {
+	APIBaseURL string   `yaml:"api_base_url"`
+	Timeout    Duration `yaml:"timeout"`
+	// TimeoutSeconds is deprecated: use Timeout (e.g. `timeout: 15s`)
+	// instead. Still parsed for one release so existing config.yaml files
+	// keep working; GetTimeout logs a warning and falls back to it when
+	// Timeout isn't set.
+	TimeoutSeconds int              `yaml:"timeout_seconds"`
+	UserAgent      string           `yaml:"user_agent"`
+	BearerToken    string           `yaml:"bearer_token"`
+	OAuthToken     string           `yaml:"oauth_token"`
+	BasicAuth      *BasicAuthConfig `yaml:"basic_auth,omitempty"`
+	// Retry controls the client's retry/backoff behavior on transient
+	// failures. Zero-valued (e.g. a registry added via a conf.d snippet that
+	// doesn't set it) falls back to DefaultRetryConfig(); see GetRetry.
+	Retry RetryConfig `yaml:"retry"`
+}
+
+// registryEnvOverrides mirrors the subset of RegistryConfig that can be set
+// via environment variables. Unlike Registries (a map), a fixed set of env
+// vars can only ever target one registry at a time — the DefaultRegistry —
+// which covers the common single-registry deployment.
+type registryEnvOverrides struct {
+	APIBaseURL string `env:"QUAY_API_BASE_URL"`
+	// TimeoutSeconds is QUAY_TIMEOUT's integer-seconds value; WithEnvironment
+	// converts it into the target registry's Timeout (a Duration), not its
+	// deprecated TimeoutSeconds field.
+	TimeoutSeconds int    `env:"QUAY_TIMEOUT"`
+	UserAgent      string `env:"QUAY_USER_AGENT"`
+}
+
+// BackendConfig describes the scanner backend to use for one registry
+// hostname (e.g. "ghcr.io"), keyed under AppConfig.Backends.
+type BackendConfig struct {
+	// Type selects the backend implementation: "quay" (the legacy Quay API
+	// client), "clairv4", or "trivy-server".
+	Type string `yaml:"type"`
+	// RegistryBaseURL is the OCI Distribution v2 endpoint used for digest
+	// resolution. For Type: "quay" it is instead the Quay API base URL
+	// (e.g. "https://quay.corp.example/api/v1/"), which resolves digests
+	// through the Quay API itself.
+	RegistryBaseURL string `yaml:"registry_base_url"`
+	// ReportBaseURL is the Clair v4 or Trivy-server endpoint used for
+	// vulnerability data. Ignored for Type: "quay".
+	ReportBaseURL  string `yaml:"report_base_url"`
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
 }
 
 // AppConfig is the top-level configuration structure
 type AppConfig struct {
-	Quay QuayConfig `yaml:"quay"`
+	// Registries holds every configured Quay (or Quay-compatible) registry,
+	// keyed by a name chosen by the operator (e.g. "quay.io",
+	// "internal-quay"). Selected via the -registry flag; DefaultRegistry
+	// names the one used when -registry isn't passed.
+	Registries      map[string]RegistryConfig `yaml:"registries"`
+	DefaultRegistry string                    `yaml:"default_registry"`
+	Policy          policy.Config             `yaml:"policy"`
+	Notify          notifier.Config           `yaml:"notify"`
+	Backends        map[string]BackendConfig  `yaml:"backends"`
+	// Platforms is the default manifest-list platform filter (e.g.
+	// ["linux/amd64", "linux/arm64"]), overridden by the -platforms flag.
+	// Empty means scan every child platform.
+	Platforms []string `yaml:"platforms" env:"QUAY_SCANNER_PLATFORMS" envSeparator:","`
 	// Add other configuration sections here (e.g., logging, defaults) if needed
 }
 
 // DefaultConfig returns a configuration with default values.
 func DefaultConfig() AppConfig {
 	return AppConfig{
-		Quay: QuayConfig{
-			APIBaseURL:     "https://quay.io/api/v1/",      // Default Quay API URL
-			TimeoutSeconds: 15,                             // Default timeout in seconds
-			UserAgent:      "golang-quay-vuln-scanner/1.1", // Updated default agent
+		Registries: map[string]RegistryConfig{
+			defaultRegistryName: {
+				APIBaseURL: "https://quay.io/api/v1/",      // Default Quay API URL
+				Timeout:    Duration(15 * time.Second),     // Default timeout
+				UserAgent:  "golang-quay-vuln-scanner/1.1", // Updated default agent
+				Retry:      DefaultRetryConfig(),
+			},
 		},
+		DefaultRegistry: defaultRegistryName,
+		// Policy is left zero-valued (disabled) by default; set `policy.fail_on`
+		// in config.yaml to turn on fail-on-severity gating.
+	}
+}
+
+// Registry returns the named registry config, or the DefaultRegistry's if
+// name is empty, and whether it was found.
+func (c AppConfig) Registry(name string) (RegistryConfig, bool) {
+	if name == "" {
+		name = c.DefaultRegistry
+	}
+	reg, ok := c.Registries[name]
+	return reg, ok
+}
+
+// GetTimeout returns the registry's request timeout, preferring Timeout and
+// falling back to the deprecated TimeoutSeconds (with a warning), or a
+// minimum fallback if neither is set.
+func (rc RegistryConfig) GetTimeout() time.Duration {
+	if rc.Timeout > 0 {
+		return time.Duration(rc.Timeout)
+	}
+	if rc.TimeoutSeconds > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: timeout_seconds is deprecated, use timeout (e.g. \"15s\") instead\n")
+		return time.Duration(rc.TimeoutSeconds) * time.Second
 	}
+	return 5 * time.Second // Minimum fallback timeout
 }
 
-// GetTimeout converts TimeoutSeconds to time.Duration
-func (qc QuayConfig) GetTimeout() time.Duration {
-	// Ensure a minimum reasonable timeout if config is invalid
-	if qc.TimeoutSeconds <= 0 {
-		return 5 * time.Second // Minimum fallback timeout
+// GetRetry returns the registry's retry policy, falling back to
+// DefaultRetryConfig() if Retry wasn't configured (MaxAttempts <= 0).
+func (rc RegistryConfig) GetRetry() RetryConfig {
+	if rc.Retry.MaxAttempts <= 0 {
+		return DefaultRetryConfig()
 	}
-	return time.Duration(qc.TimeoutSeconds) * time.Second
+	return rc.Retry
 }
 
 // LoadConfig reads the configuration file or returns defaults.
@@ -60,6 +219,9 @@ func LoadConfig(filePath string) (AppConfig, error) {
 		if os.IsNotExist(err) {
 			// Config file doesn't exist is not a fatal error, use defaults
 			fmt.Fprintf(os.Stderr, "Info: Config file '%s' not found, using default settings.\n", absPath)
+			if err := WithEnvironment(&cfg); err != nil {
+				return cfg, fmt.Errorf("failed to apply environment overrides: %w", err)
+			}
 			return cfg, nil
 		}
 		// Other file reading error *is* potentially fatal or indicates misconfiguration
@@ -72,25 +234,235 @@ func LoadConfig(filePath string) (AppConfig, error) {
 		return cfg, fmt.Errorf("failed to parse YAML config file '%s': %w", absPath, err)
 	}
 
+	// Merge any conf.d/*.yaml snippets sitting next to the primary file, so
+	// packagers can ship a base config and let users drop registry-specific
+	// overrides in without editing it.
+	cfg, err = mergeConfDir(cfg, filepath.Join(filepath.Dir(absPath), "conf.d"))
+	if err != nil {
+		return cfg, fmt.Errorf("failed to merge conf.d overrides for '%s': %w", absPath, err)
+	}
+
+	// Environment variables take precedence over the YAML file, so operators
+	// can override a baked-in config.yaml at `docker run -e ...` time.
+	if err := WithEnvironment(&cfg); err != nil {
+		return cfg, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
+
+	expandRegistryCredentials(cfg.Registries)
+
 	// --- Basic Validation ---
-	if cfg.Quay.APIBaseURL == "" {
-		fmt.Fprintf(os.Stderr, "Warning: quay.api_base_url is empty in config '%s', using default: %s\n", absPath, DefaultConfig().Quay.APIBaseURL)
-		cfg.Quay.APIBaseURL = DefaultConfig().Quay.APIBaseURL
+	if cfg.DefaultRegistry == "" {
+		cfg.DefaultRegistry = defaultRegistryName
 	}
-	if !isValidURL(cfg.Quay.APIBaseURL) {
+	defaultReg, ok := cfg.Registries[cfg.DefaultRegistry]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Warning: default_registry '%s' not found in config '%s', using built-in default.\n", cfg.DefaultRegistry, absPath)
+		cfg.DefaultRegistry = defaultRegistryName
+		defaultReg = DefaultConfig().Registries[defaultRegistryName]
+	}
+	if defaultReg.APIBaseURL == "" {
+		fmt.Fprintf(os.Stderr, "Warning: registries.%s.api_base_url is empty in config '%s', using default.\n", cfg.DefaultRegistry, absPath)
+		defaultReg.APIBaseURL = DefaultConfig().Registries[defaultRegistryName].APIBaseURL
+	}
+	if !isValidURL(defaultReg.APIBaseURL) {
 		// Or return an error if URL must be valid
-		fmt.Fprintf(os.Stderr, "Warning: quay.api_base_url ('%s') in config '%s' might be invalid, attempting to use anyway.\n", cfg.Quay.APIBaseURL, absPath)
+		fmt.Fprintf(os.Stderr, "Warning: registries.%s.api_base_url ('%s') in config '%s' might be invalid, attempting to use anyway.\n", cfg.DefaultRegistry, defaultReg.APIBaseURL, absPath)
 	}
-	if cfg.Quay.TimeoutSeconds <= 0 {
-		fmt.Fprintf(os.Stderr, "Warning: quay.timeout_seconds must be positive in config '%s', using default: %d\n", absPath, DefaultConfig().Quay.TimeoutSeconds)
-		cfg.Quay.TimeoutSeconds = DefaultConfig().Quay.TimeoutSeconds
+	if defaultReg.Timeout <= 0 && defaultReg.TimeoutSeconds <= 0 {
+		fmt.Fprintf(os.Stderr, "Warning: registries.%s.timeout must be positive in config '%s', using default.\n", cfg.DefaultRegistry, absPath)
+		defaultReg.Timeout = DefaultConfig().Registries[defaultRegistryName].Timeout
 	}
 	// UserAgent can reasonably be empty, so no strict validation unless required.
+	cfg.Registries[cfg.DefaultRegistry] = defaultReg
 
 	fmt.Fprintf(os.Stderr, "Info: Loaded configuration from '%s'\n", absPath)
 	return cfg, nil
 }
 
+// LoadDefault locates a config file without an explicit -config flag and
+// loads it via LoadConfig. It probes, in order: $QUAYSCANNER_CONFIG,
+// ./config.yaml, $XDG_CONFIG_HOME/quay-scanner/config.yaml (falling back to
+// ~/.config/quay-scanner/config.yaml), and /etc/quay-scanner/config.yaml.
+// The first path that exists wins; if none exist, LoadConfig's own "file not
+// found" handling applies to the last candidate, which returns defaults.
+func LoadDefault() (AppConfig, error) {
+	return LoadConfig(findDefaultConfigPath())
+}
+
+// ResolveConfigPath returns the config file path LoadConfig/LoadDefault will
+// read for a given -config flag value: explicit if non-empty, otherwise
+// whatever LoadDefault's search would resolve to. config.Watcher uses this
+// to know which file to re-read on a SIGHUP-triggered reload.
+func ResolveConfigPath(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return findDefaultConfigPath()
+}
+
+// findDefaultConfigPath implements the search order documented on
+// LoadDefault, returning the first candidate that exists, or the last
+// candidate (letting LoadConfig's "file not found" handling return defaults)
+// if none do.
+func findDefaultConfigPath() string {
+	candidates := []string{}
+	if p := os.Getenv(envConfigPath); p != "" {
+		candidates = append(candidates, p)
+	}
+	candidates = append(candidates, "config.yaml")
+
+	if xdgHome := os.Getenv("XDG_CONFIG_HOME"); xdgHome != "" {
+		candidates = append(candidates, filepath.Join(xdgHome, "quay-scanner", "config.yaml"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".config", "quay-scanner", "config.yaml"))
+	}
+
+	candidates = append(candidates, filepath.Join("/etc", "quay-scanner", "config.yaml"))
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// mergeConfDir globs confDir/*.yaml in lexical order and recursively merges
+// each into cfg: maps merge key-by-key, scalars and slices are replaced
+// wholesale by later files. A missing conf.d directory is not an error.
+func mergeConfDir(cfg AppConfig, confDir string) (AppConfig, error) {
+	snippets, err := filepath.Glob(filepath.Join(confDir, "*.yaml"))
+	if err != nil {
+		return cfg, fmt.Errorf("globbing '%s': %w", confDir, err)
+	}
+	if len(snippets) == 0 {
+		return cfg, nil
+	}
+	sort.Strings(snippets)
+
+	base, err := toYAMLMap(cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("preparing base config for merge: %w", err)
+	}
+
+	for _, snippet := range snippets {
+		data, err := os.ReadFile(snippet)
+		if err != nil {
+			return cfg, fmt.Errorf("reading '%s': %w", snippet, err)
+		}
+		var overlay map[string]interface{}
+		if err := yaml.Unmarshal(data, &overlay); err != nil {
+			return cfg, fmt.Errorf("parsing '%s': %w", snippet, err)
+		}
+		base = mergeYAMLMaps(base, overlay)
+		fmt.Fprintf(os.Stderr, "Info: Merged config override '%s'\n", snippet)
+	}
+
+	merged, err := yaml.Marshal(base)
+	if err != nil {
+		return cfg, fmt.Errorf("re-marshaling merged config: %w", err)
+	}
+	var result AppConfig
+	if err := yaml.Unmarshal(merged, &result); err != nil {
+		return cfg, fmt.Errorf("parsing merged config: %w", err)
+	}
+	return result, nil
+}
+
+// toYAMLMap round-trips v through YAML to get its generic map[string]interface{}
+// representation, so it can be merged key-by-key with conf.d overlays.
+func toYAMLMap(v interface{}) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mergeYAMLMaps merges overlay into base: keys present only in base are
+// kept, keys present in overlay replace base's unless both sides are maps,
+// in which case they're merged recursively. Slices and scalars are always
+// replaced wholesale by overlay's value.
+func mergeYAMLMaps(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, overlayVal := range overlay {
+		baseVal, exists := base[key]
+		if exists {
+			baseMap, baseIsMap := baseVal.(map[string]interface{})
+			overlayMap, overlayIsMap := overlayVal.(map[string]interface{})
+			if baseIsMap && overlayIsMap {
+				base[key] = mergeYAMLMaps(baseMap, overlayMap)
+				continue
+			}
+		}
+		base[key] = overlayVal
+	}
+	return base
+}
+
+// WithEnvironment overlays environment variable overrides onto cfg, for any
+// field tagged `env:"..."` (see AppConfig.Platforms), plus QUAY_API_BASE_URL
+// / QUAY_TIMEOUT / QUAY_USER_AGENT overrides applied to the DefaultRegistry
+// entry (a fixed set of env vars can't address an arbitrary registry name).
+// It runs after the YAML file is loaded, so environment variables win over
+// both built-in defaults and config.yaml — letting a single scanner binary
+// be configured via `docker run -e ...` the same way as a config file.
+func WithEnvironment(cfg *AppConfig) error {
+	if err := env.Parse(cfg); err != nil {
+		return err
+	}
+
+	var overrides registryEnvOverrides
+	if err := env.Parse(&overrides); err != nil {
+		return err
+	}
+	if overrides == (registryEnvOverrides{}) {
+		return nil
+	}
+
+	name := cfg.DefaultRegistry
+	if name == "" {
+		name = defaultRegistryName
+	}
+	if cfg.Registries == nil {
+		cfg.Registries = map[string]RegistryConfig{}
+	}
+	reg := cfg.Registries[name]
+	if overrides.APIBaseURL != "" {
+		reg.APIBaseURL = overrides.APIBaseURL
+	}
+	if overrides.TimeoutSeconds != 0 {
+		// Write straight into Timeout (the Duration GetTimeout prefers), not
+		// the deprecated TimeoutSeconds: DefaultConfig already sets Timeout,
+		// so writing TimeoutSeconds here would be shadowed and never take
+		// effect.
+		reg.Timeout = Duration(time.Duration(overrides.TimeoutSeconds) * time.Second)
+	}
+	if overrides.UserAgent != "" {
+		reg.UserAgent = overrides.UserAgent
+	}
+	cfg.Registries[name] = reg
+	return nil
+}
+
+// expandRegistryCredentials expands ${ENV_VAR} references in every
+// registry's credential fields, so operators can reference secrets from the
+// environment instead of writing them into config.yaml.
+func expandRegistryCredentials(registries map[string]RegistryConfig) {
+	for name, reg := range registries {
+		reg.BearerToken = os.ExpandEnv(reg.BearerToken)
+		reg.OAuthToken = os.ExpandEnv(reg.OAuthToken)
+		if reg.BasicAuth != nil {
+			reg.BasicAuth.Username = os.ExpandEnv(reg.BasicAuth.Username)
+			reg.BasicAuth.Password = os.ExpandEnv(reg.BasicAuth.Password)
+		}
+		registries[name] = reg
+	}
+}
+
 // isValidURL is a basic check (can be expanded if needed)
 func isValidURL(u string) bool {
 	// Very basic check, net/url.Parse is more robust but might allow relative paths etc.
@@ -0,0 +1,105 @@
+// internal/config/watcher.go
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Watcher re-runs LoadConfig on SIGHUP and notifies subscribers with the
+// previous and newly loaded AppConfig, following the same pattern as
+// Prometheus's reloadConfig: validate first, and only swap in the new
+// config (and tell anyone who's listening) if validation succeeds. This
+// lets a scanner running as a daemon/sidecar pick up registry, timeout,
+// retry, and credential changes without restarting in-flight scans.
+type Watcher struct {
+	filePath string
+
+	mu  sync.RWMutex
+	cfg AppConfig
+
+	subsMu sync.Mutex
+	subs   []func(old, new AppConfig)
+}
+
+// NewWatcher creates a Watcher around an already-loaded config and the file
+// path it came from. filePath should be the same path passed to LoadConfig
+// (or the one LoadDefault resolved); if empty, Reload does nothing, since
+// there's nothing on disk to re-read, and Start still traps SIGHUP and
+// blocks until its context is canceled but never swaps in a new config.
+func NewWatcher(filePath string, initial AppConfig) *Watcher {
+	return &Watcher{filePath: filePath, cfg: initial}
+}
+
+// Current returns the most recently loaded config.
+func (w *Watcher) Current() AppConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// Subscribe registers fn to be called with the old and new config every
+// time Reload succeeds. fn runs synchronously on the goroutine that
+// triggered the reload (Start's SIGHUP handling), so subscribers should
+// do their own work quickly, e.g. quay.Client.UpdateConfig.
+func (w *Watcher) Subscribe(fn func(old, new AppConfig)) {
+	w.subsMu.Lock()
+	defer w.subsMu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Reload re-runs LoadConfig against filePath. Only if it succeeds does it
+// swap in the new config and notify subscribers; a failed reload (e.g. a
+// syntax error introduced while hand-editing config.yaml) leaves the
+// previous, already-validated config and its subscribers untouched.
+func (w *Watcher) Reload() error {
+	if w.filePath == "" {
+		return nil
+	}
+
+	next, err := LoadConfig(w.filePath)
+	if err != nil {
+		return fmt.Errorf("reload of '%s' failed, keeping previous configuration: %w", w.filePath, err)
+	}
+
+	w.mu.Lock()
+	old := w.cfg
+	w.cfg = next
+	w.mu.Unlock()
+
+	w.subsMu.Lock()
+	subs := make([]func(old, new AppConfig), len(w.subs))
+	copy(subs, w.subs)
+	w.subsMu.Unlock()
+
+	for _, sub := range subs {
+		sub(old, next)
+	}
+	return nil
+}
+
+// Start blocks until ctx is canceled, calling Reload on every SIGHUP the
+// process receives and logging (rather than returning) any reload error, so
+// a daemon doesn't die because someone wrote an invalid config.yaml.
+func (w *Watcher) Start(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			log.Printf("Info: received SIGHUP, reloading configuration from '%s'", w.filePath)
+			if err := w.Reload(); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
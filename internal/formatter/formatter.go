@@ -8,22 +8,51 @@ import (
 	"strings"
 	"text/tabwriter"
 
-	"quay-scanner/internal/quay" // Adjust import path if needed
+	"quay-scanner/internal/policy"
+	"quay-scanner/internal/quay"
+	"quay-scanner/internal/report"
 )
 
-// FormatJSON outputs the aggregated results as indented JSON.
-// Input is expected to be map[string]quay.ImageScanResult
-func FormatJSON(w io.Writer, results map[string]quay.ImageScanResult) error {
+// jsonReport is the shape written out by FormatJSON: the existing per-image
+// results map, plus the cross-image summary and (when policy gating is
+// enabled) a verdict per image. Results is omitted entirely in summary-only
+// mode.
+type jsonReport struct {
+	Summary  report.Summary                  `json:"summary"`
+	Results  map[string]quay.ImageScanResult `json:"results,omitempty"`
+	Verdicts map[string]policy.PolicyVerdict `json:"policyVerdicts,omitempty"`
+}
+
+// FormatJSON outputs the aggregate summary, plus (unless summaryOnly) the
+// per-image results, as indented JSON. policyVerdicts is only included when
+// policyEnabled, so a run with no `policy:` block configured doesn't emit a
+// policyVerdicts field full of vacuous passes.
+func FormatJSON(w io.Writer, results map[string]quay.ImageScanResult, verdicts map[string]policy.PolicyVerdict, summary report.Summary, summaryOnly, policyEnabled bool) error {
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(results); err != nil {
+	doc := jsonReport{Summary: summary}
+	if !summaryOnly {
+		doc.Results = results
+		if policyEnabled {
+			doc.Verdicts = verdicts
+		}
+	}
+	if err := encoder.Encode(doc); err != nil {
 		return fmt.Errorf("failed to marshal results to JSON: %w", err)
 	}
 	return nil
 }
 
-// FormatHumanReadable outputs the results for multiple images.
-func FormatHumanReadable(w io.Writer, results map[string]quay.ImageScanResult) {
+// FormatHumanReadable prints the aggregate summary followed by, unless
+// summaryOnly is set, the per-image detail. The policy verdict line is only
+// printed when policyEnabled, so an unconfigured policy stays silent.
+func FormatHumanReadable(w io.Writer, results map[string]quay.ImageScanResult, verdicts map[string]policy.PolicyVerdict, summary report.Summary, summaryOnly, policyEnabled bool) {
+	printSummary(w, summary)
+
+	if summaryOnly {
+		return
+	}
+
 	// Sort image URLs for consistent output order
 	urls := make([]string, 0, len(results))
 	for k := range results {
@@ -31,74 +60,132 @@ func FormatHumanReadable(w io.Writer, results map[string]quay.ImageScanResult) {
 	}
 	sort.Strings(urls)
 
-	firstImage := true
 	for _, imageURL := range urls {
 		result := results[imageURL]
 
-		if !firstImage {
-			fmt.Fprintln(w, "\n"+strings.Repeat("=", 80)) // Separator
-		}
-		firstImage = false
+		fmt.Fprintln(w, "\n"+strings.Repeat("=", 80)) // Separator
 
 		fmt.Fprintf(w, "Scan Report for: %s\n", result.ImageURL)
 		fmt.Fprintln(w, strings.Repeat("-", len(result.ImageURL)+17)) // Underline
 
-		if result.Error != "" {
-			fmt.Fprintf(w, "  Error: %s\n", result.Error)
-			continue // Move to the next image
+		if policyEnabled {
+			if verdict, ok := verdicts[imageURL]; ok {
+				printPolicyVerdict(w, verdict)
+			}
 		}
 
-		if result.Report == nil {
-			fmt.Fprintln(w, "  Error: No report data available (internal error).")
-			continue
+		// A manifest-list parent carries no Report of its own, only its
+		// per-platform Children, so there's no body to print for it.
+		if result.Report != nil || result.Error != "" || len(result.Children) == 0 {
+			printImageBody(w, result, "  ")
 		}
 
-		fmt.Fprintf(w, "  Scan Status: %s\n", result.Report.Status)
+		if len(result.Children) > 0 {
+			platforms := make([]string, 0, len(result.Children))
+			for platform := range result.Children {
+				platforms = append(platforms, platform)
+			}
+			sort.Strings(platforms)
 
-		if result.Report.Status != "scanned" {
-			fmt.Fprintln(w, "  No detailed vulnerability data available (scan may be queued or failed).")
-			continue
+			for _, platform := range platforms {
+				fmt.Fprintf(w, "\n  Platform: %s\n", platform)
+				printImageBody(w, *result.Children[platform], "    ")
+			}
 		}
+	}
+}
 
-		if result.Report.Data.Layer.Features == nil || len(result.Report.Data.Layer.Features) == 0 {
-			fmt.Fprintln(w, "  No features with vulnerabilities found in the scan data.")
-			continue
+// printSummary renders the cross-image rollup shown before per-image detail
+// (or alone, in --summary-only mode).
+func printSummary(w io.Writer, summary report.Summary) {
+	fmt.Fprintln(w, "Summary")
+	fmt.Fprintln(w, strings.Repeat("=", 7))
+	fmt.Fprintf(w, "  Images scanned: %d (failed: %d)\n", summary.ImagesScanned, summary.ImagesFailed)
+	fmt.Fprintf(w, "  Unique CVEs: %d\n", summary.UniqueCVEs)
+	fmt.Fprintf(w, "  Severity counts: Critical=%d High=%d Medium=%d Low=%d Negligible=%d Unknown=%d\n",
+		summary.SeverityCounts.Critical, summary.SeverityCounts.High, summary.SeverityCounts.Medium,
+		summary.SeverityCounts.Low, summary.SeverityCounts.Negligible, summary.SeverityCounts.Unknown)
+	fmt.Fprintf(w, "  Fixability ratio: %.0f%%\n", summary.FixabilityRatio*100)
+	if len(summary.TopPackages) > 0 {
+		fmt.Fprintln(w, "  Most-affected packages:")
+		for _, pkg := range summary.TopPackages {
+			fmt.Fprintf(w, "    - %s (%d)\n", pkg.Package, pkg.Count)
 		}
+	}
+}
 
-		vulnerabilitiesFound := false
-		// Use tabwriter for aligned columns
-		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) // Indent using spaces in Fprintf
-		fmt.Fprintln(tw, "  CVE\tSeverity\tPackage\tVersion\tFixed By\tLink")
-		fmt.Fprintln(tw, "  ---\t--------\t-------\t-------\t--------\t----")
-
-		for _, feature := range result.Report.Data.Layer.Features {
-			if len(feature.Vulnerabilities) > 0 {
-				vulnerabilitiesFound = true
-				for _, vuln := range feature.Vulnerabilities {
-					fixedBy := vuln.FixedBy
-					if fixedBy == "" {
-						fixedBy = "N/A"
-					}
-					// Indent each line of the table
-					fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n",
-						vuln.Name,
-						vuln.Severity,
-						feature.Name,
-						feature.Version,
-						fixedBy,
-						vuln.Link,
-					)
+// printImageBody renders the error/status/vulnerability-table body shared by
+// top-level images and, indented further, their manifest-list children.
+func printImageBody(w io.Writer, result quay.ImageScanResult, indent string) {
+	if result.Error != "" {
+		fmt.Fprintf(w, "%sError: %s\n", indent, result.Error)
+		return
+	}
+
+	if result.Report == nil {
+		fmt.Fprintf(w, "%sError: No report data available (internal error).\n", indent)
+		return
+	}
+
+	fmt.Fprintf(w, "%sScan Status: %s\n", indent, result.Report.Status)
+
+	if result.Report.Status != "scanned" {
+		fmt.Fprintf(w, "%sNo detailed vulnerability data available (scan may be queued or failed).\n", indent)
+		return
+	}
+
+	if len(result.Report.Data.Layer.Features) == 0 {
+		fmt.Fprintf(w, "%sNo features with vulnerabilities found in the scan data.\n", indent)
+		return
+	}
+
+	vulnerabilitiesFound := false
+	// Use tabwriter for aligned columns
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) // Indent using spaces in Fprintf
+	fmt.Fprintf(tw, "%sCVE\tSeverity\tPackage\tVersion\tFixed By\tLink\n", indent)
+	fmt.Fprintf(tw, "%s---\t--------\t-------\t-------\t--------\t----\n", indent)
+
+	for _, feature := range result.Report.Data.Layer.Features {
+		if len(feature.Vulnerabilities) > 0 {
+			vulnerabilitiesFound = true
+			for _, vuln := range feature.Vulnerabilities {
+				fixedBy := vuln.FixedBy
+				if fixedBy == "" {
+					fixedBy = "N/A"
 				}
+				// Indent each line of the table
+				fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\t%s\n",
+					indent,
+					vuln.Name,
+					vuln.Severity,
+					feature.Name,
+					feature.Version,
+					fixedBy,
+					vuln.Link,
+				)
 			}
 		}
+	}
 
-		if !vulnerabilitiesFound {
-			fmt.Fprintln(tw) // Flush preamble if no vulns found
-			tw.Flush()
-			fmt.Fprintln(w, "\n  No vulnerabilities found for this image.")
-		} else {
-			fmt.Fprintln(tw) // Add a newline at the end
-			tw.Flush()       // Flush the buffer to print the table
-		}
+	if !vulnerabilitiesFound {
+		fmt.Fprintln(tw) // Flush preamble if no vulns found
+		tw.Flush()
+		fmt.Fprintf(w, "\n%sNo vulnerabilities found for this image.\n", indent)
+	} else {
+		fmt.Fprintln(tw) // Add a newline at the end
+		tw.Flush()       // Flush the buffer to print the table
+	}
+}
+
+// printPolicyVerdict renders a policy.PolicyVerdict as a short block above
+// the vulnerability table for an image.
+func printPolicyVerdict(w io.Writer, verdict policy.PolicyVerdict) {
+	if verdict.Pass {
+		fmt.Fprintln(w, "  Policy: PASS")
+		return
+	}
+	fmt.Fprintf(w, "  Policy: FAIL (%s)\n", verdict.Reason)
+	for _, v := range verdict.Violations {
+		fmt.Fprintf(w, "    - %s [%s] in %s\n", v.CVE, v.Severity, v.Package)
 	}
 }
@@ -0,0 +1,80 @@
+// internal/formatter/ndjson.go
+package formatter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+
+	"quay-scanner/internal/quay"
+	"quay-scanner/internal/report"
+)
+
+// Syncer is implemented by writers that can flush buffered data down to the
+// underlying device (os.Stdout satisfies it via Sync). writeNDJSONRecord
+// uses it so downstream `jq` or log shippers see each record live instead of
+// waiting on Go's normal stdio buffering.
+type Syncer interface {
+	Sync() error
+}
+
+// ndjsonImageRecord is one line of NDJSON output for a completed image scan.
+type ndjsonImageRecord struct {
+	Type string `json:"type"`
+	quay.ImageScanResult
+}
+
+// ndjsonSummaryRecord is the final NDJSON line, emitted once every image has
+// been scanned, mirroring how Docker's stream formatter emits an interleaved
+// terminal-status frame after its progress frames.
+type ndjsonSummaryRecord struct {
+	Type string `json:"type"`
+	report.Summary
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+}
+
+// WriteNDJSONImage writes a single "image" record for result to w and
+// flushes it immediately.
+func WriteNDJSONImage(w io.Writer, result quay.ImageScanResult) error {
+	return writeNDJSONRecord(w, ndjsonImageRecord{Type: "image", ImageScanResult: result})
+}
+
+// WriteNDJSONSummary writes the terminal "summary" record for a completed
+// run, carrying the same cross-image rollup as the human/JSON formatters and
+// the webhook notifier's run_summary event, plus total elapsed time.
+func WriteNDJSONSummary(w io.Writer, summary report.Summary, elapsed time.Duration) error {
+	record := ndjsonSummaryRecord{
+		Type:           "summary",
+		Summary:        summary,
+		ElapsedSeconds: elapsed.Seconds(),
+	}
+	return writeNDJSONRecord(w, record)
+}
+
+// writeNDJSONRecord encodes v as a single JSON line and, if w supports it,
+// flushes it to the underlying device before returning.
+func writeNDJSONRecord(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to marshal NDJSON record: %w", err)
+	}
+	if syncer, ok := w.(Syncer); ok {
+		if err := syncer.Sync(); err != nil && !isIgnorableSyncError(err) {
+			return fmt.Errorf("failed to flush NDJSON record: %w", err)
+		}
+	}
+	return nil
+}
+
+// isIgnorableSyncError reports whether err is the kind of Sync failure that
+// just means the underlying fd can't be flushed at all rather than that the
+// record wasn't written: piping stdout to `jq`/a log shipper makes it a pipe,
+// and pipes (and some other non-regular files) return EINVAL/ENOTSUP from
+// fsync on Linux. Encode has already written the bytes, so this is safe to
+// treat as a no-op instead of failing the whole streaming run over it.
+func isIgnorableSyncError(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP)
+}
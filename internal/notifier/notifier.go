@@ -0,0 +1,264 @@
+// internal/notifier/notifier.go
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"quay-scanner/internal/quay"
+	"quay-scanner/internal/report"
+)
+
+// scannerVersion is reported in every emitted event's metadata. Kept in sync
+// with the user agent default in internal/config.
+const scannerVersion = "1.1"
+
+// signatureHeader is the header carrying the HMAC-SHA256 signature of the
+// payload body, when a target has a SigningSecret configured.
+const signatureHeader = "X-Quay-Scanner-Signature"
+
+// Target describes a single webhook endpoint to notify.
+type Target struct {
+	URL               string            `yaml:"url"`
+	Method            string            `yaml:"method"` // defaults to POST
+	Headers           map[string]string `yaml:"headers"`
+	SigningSecret     string            `yaml:"signing_secret"` // optional, HMAC-SHA256
+	SeverityThreshold string            `yaml:"severity_threshold"`
+	MaxAttempts       int               `yaml:"max_attempts"`
+	InitialBackoffMS  int               `yaml:"initial_backoff_ms"`
+}
+
+// Config holds the notifier settings loaded from config.yaml's `notify` section.
+type Config struct {
+	Targets []Target `yaml:"targets"`
+}
+
+// Enabled reports whether any webhook targets have been configured.
+func (c Config) Enabled() bool {
+	return len(c.Targets) > 0
+}
+
+// eventType distinguishes per-image events from the end-of-run summary event.
+type eventType string
+
+const (
+	eventTypeScan    eventType = "scan_complete"
+	eventTypeSummary eventType = "run_summary"
+)
+
+// event is the stable JSON schema sent to every webhook target.
+type event struct {
+	EventID        string                `json:"eventId"`
+	EventType      eventType             `json:"eventType"`
+	Timestamp      time.Time             `json:"timestamp"`
+	ScannerVersion string                `json:"scannerVersion"`
+	Image          *quay.ImageScanResult `json:"image,omitempty"`
+	Summary        *report.Summary       `json:"summary,omitempty"`
+}
+
+// Notifier emits scan events to configured webhook targets.
+type Notifier struct {
+	cfg        Config
+	httpClient *http.Client
+	nextID     int
+	inFlight   sync.WaitGroup
+}
+
+// New creates a Notifier from cfg. A zero-value Config is valid and produces
+// a Notifier that silently does nothing.
+func New(cfg Config) *Notifier {
+	return &Notifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NotifyScan emits a scan_complete event for a single image to every target
+// whose severity threshold the result meets or exceeds. Delivery happens on
+// its own goroutine per target so a slow or failing webhook can't stall the
+// result collector calling this, which also drives NDJSON streaming; call
+// Wait before the process exits to give in-flight deliveries a chance to
+// finish.
+func (n *Notifier) NotifyScan(result quay.ImageScanResult) {
+	if !n.cfg.Enabled() {
+		return
+	}
+	evt := n.newEvent(eventTypeScan)
+	evt.Image = &result
+
+	for _, target := range n.cfg.Targets {
+		if !meetsThreshold(result, target.SeverityThreshold) {
+			continue
+		}
+		n.dispatch(target, evt)
+	}
+}
+
+// NotifySummary emits a run_summary event to every configured target once all
+// images have been scanned. Like NotifyScan, delivery is asynchronous; call
+// Wait afterwards to let it complete before exiting.
+func (n *Notifier) NotifySummary(summary report.Summary) {
+	if !n.cfg.Enabled() {
+		return
+	}
+	evt := n.newEvent(eventTypeSummary)
+	evt.Summary = &summary
+
+	for _, target := range n.cfg.Targets {
+		n.dispatch(target, evt)
+	}
+}
+
+// dispatch runs send on its own goroutine, tracked by n.inFlight so Wait can
+// block until every outstanding delivery (success, failure, or exhausted
+// retries) has finished.
+func (n *Notifier) dispatch(target Target, evt event) {
+	n.inFlight.Add(1)
+	go func() {
+		defer n.inFlight.Done()
+		n.send(target, evt)
+	}()
+}
+
+// Wait blocks until every event dispatched so far has finished being
+// delivered (or has exhausted its retries). Callers should invoke this after
+// the last NotifySummary so the process doesn't exit mid-delivery.
+func (n *Notifier) Wait() {
+	n.inFlight.Wait()
+}
+
+func (n *Notifier) newEvent(t eventType) event {
+	n.nextID++
+	return event{
+		EventID:        fmt.Sprintf("evt-%d", n.nextID),
+		EventType:      t,
+		Timestamp:      time.Now().UTC(),
+		ScannerVersion: scannerVersion,
+	}
+}
+
+// meetsThreshold reports whether result (or, for a manifest list, any of its
+// per-platform Children) contains a vulnerability at or above threshold
+// severity. An empty threshold matches everything.
+func meetsThreshold(result quay.ImageScanResult, threshold string) bool {
+	if threshold == "" {
+		return true
+	}
+	if result.Error != "" {
+		return true // always notify on scan errors
+	}
+	want := severityRank(threshold)
+	var walk func(r quay.ImageScanResult) bool
+	walk = func(r quay.ImageScanResult) bool {
+		if r.Report != nil {
+			for _, feature := range r.Report.Data.Layer.Features {
+				for _, vuln := range feature.Vulnerabilities {
+					if severityRank(vuln.Severity) >= want {
+						return true
+					}
+				}
+			}
+		}
+		for _, child := range r.Children {
+			if child != nil && walk(*child) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(result)
+}
+
+var severityOrder = map[string]int{
+	"Unknown":    0,
+	"Negligible": 1,
+	"Low":        2,
+	"Medium":     3,
+	"High":       4,
+	"Critical":   5,
+}
+
+func severityRank(severity string) int {
+	if rank, ok := severityOrder[severity]; ok {
+		return rank
+	}
+	return severityOrder["Unknown"]
+}
+
+// send delivers evt to target, retrying transient failures per the target's
+// MaxAttempts/InitialBackoffMS, then logging (not failing the scan) on
+// exhaustion.
+func (n *Notifier) send(target Target, evt event) {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		log.Printf("ERROR: [notifier] failed to marshal event for %s: %v", target.URL, err)
+		return
+	}
+
+	maxAttempts := target.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	backoff := time.Duration(target.InitialBackoffMS) * time.Millisecond
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if lastErr = n.attempt(target, body); lastErr == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			log.Printf("WARN: [notifier] delivery to %s failed (attempt %d/%d): %v", target.URL, attempt, maxAttempts, lastErr)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Printf("ERROR: [notifier] giving up delivering event to %s after %d attempts: %v", target.URL, maxAttempts, lastErr)
+}
+
+func (n *Notifier) attempt(target Target, body []byte) error {
+	method := target.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+	if target.SigningSecret != "" {
+		req.Header.Set(signatureHeader, sign(target.SigningSecret, body))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("target responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// sign computes the hex-encoded HMAC-SHA256 of body using secret.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,182 @@
+// internal/policy/policy.go
+package policy
+
+import (
+	"time"
+
+	"quay-scanner/internal/quay"
+)
+
+// Severity is the Quay/Clair vulnerability severity as a string, e.g. "Critical", "High".
+type Severity string
+
+// severityRank orders severities from least to most severe so thresholds can be
+// compared. Unknown severities are treated as the lowest rank.
+var severityRank = map[string]int{
+	"Unknown":    0,
+	"Negligible": 1,
+	"Low":        2,
+	"Medium":     3,
+	"High":       4,
+	"Critical":   5,
+}
+
+// rankOf returns the numeric rank for a severity string, defaulting to the
+// "Unknown" rank for anything not recognized.
+func rankOf(severity string) int {
+	if rank, ok := severityRank[severity]; ok {
+		return rank
+	}
+	return severityRank["Unknown"]
+}
+
+// Config holds the policy settings loaded from config.yaml's `policy` section.
+// It is evaluated against every quay.ImageScanResult after scanning completes.
+type Config struct {
+	FailOn        string   `yaml:"fail_on"`
+	IgnoreCVEs    []string `yaml:"ignore_cves"`
+	IgnoreUnfixed bool     `yaml:"ignore_unfixed"`
+	// MaxAgeDays fails a result whose tag is older than this many days (see
+	// isStale). The Quay tag endpoint only exposes StartTs, the tag/manifest
+	// creation time, not when it was last scanned — so this gates on tag age,
+	// not scan freshness. A tag that's been sitting untouched for a while
+	// fails even if Clair re-scanned it yesterday.
+	MaxAgeDays int `yaml:"max_age_days"`
+}
+
+// Enabled reports whether any policy gating has been configured.
+func (c Config) Enabled() bool {
+	return c.FailOn != ""
+}
+
+// TriggeringVuln identifies a single vulnerability that caused a policy failure.
+type TriggeringVuln struct {
+	CVE      string `json:"cve"`
+	Severity string `json:"severity"`
+	Package  string `json:"package"`
+	FixedBy  string `json:"fixedBy,omitempty"`
+}
+
+// PolicyVerdict is the result of evaluating one ImageScanResult against a Config.
+type PolicyVerdict struct {
+	ImageURL   string           `json:"imageUrl"`
+	Pass       bool             `json:"pass"`
+	Reason     string           `json:"reason,omitempty"`
+	Violations []TriggeringVuln `json:"violations,omitempty"`
+}
+
+// Evaluate applies cfg to a single image's scan result, returning a PolicyVerdict.
+// A result with a scan error, or a tag older than cfg.MaxAgeDays (see isStale),
+// fails closed.
+func Evaluate(cfg Config, result quay.ImageScanResult) PolicyVerdict {
+	verdict := PolicyVerdict{ImageURL: result.ImageURL, Pass: true}
+
+	if !cfg.Enabled() {
+		return verdict
+	}
+
+	if result.Error != "" {
+		verdict.Pass = false
+		verdict.Reason = "scan error: " + result.Error
+		return verdict
+	}
+
+	if cfg.MaxAgeDays > 0 && isStale(result, cfg.MaxAgeDays) {
+		verdict.Pass = false
+		verdict.Reason = "tag is older than max_age_days"
+		return verdict
+	}
+
+	ignored := make(map[string]bool, len(cfg.IgnoreCVEs))
+	for _, cve := range cfg.IgnoreCVEs {
+		ignored[cve] = true
+	}
+
+	// Walk result and, like report.Summarize, its manifest-list Children: a
+	// parent has no Report of its own (only per-platform children do), so
+	// evaluating just result.Report would fail-closed on every multi-arch
+	// image regardless of what its children found.
+	threshold := rankOf(cfg.FailOn)
+	hasReportData := false
+	var violations []TriggeringVuln
+	var walk func(r quay.ImageScanResult)
+	walk = func(r quay.ImageScanResult) {
+		if r.Report != nil {
+			hasReportData = true
+			for _, feature := range r.Report.Data.Layer.Features {
+				for _, vuln := range feature.Vulnerabilities {
+					if ignored[vuln.Name] {
+						continue
+					}
+					if cfg.IgnoreUnfixed && vuln.FixedBy == "" {
+						continue
+					}
+					if rankOf(vuln.Severity) < threshold {
+						continue
+					}
+					violations = append(violations, TriggeringVuln{
+						CVE:      vuln.Name,
+						Severity: vuln.Severity,
+						Package:  feature.Name,
+						FixedBy:  vuln.FixedBy,
+					})
+				}
+			}
+		}
+		for _, child := range r.Children {
+			if child != nil {
+				walk(*child)
+			}
+		}
+	}
+	walk(result)
+
+	if !hasReportData {
+		verdict.Pass = false
+		verdict.Reason = "no report data available"
+		return verdict
+	}
+
+	verdict.Violations = violations
+	if len(verdict.Violations) > 0 {
+		verdict.Pass = false
+		verdict.Reason = "vulnerabilities at or above severity " + cfg.FailOn
+	}
+
+	return verdict
+}
+
+// EvaluateAll evaluates cfg against every result, keyed by image URL.
+func EvaluateAll(cfg Config, results map[string]quay.ImageScanResult) map[string]PolicyVerdict {
+	verdicts := make(map[string]PolicyVerdict, len(results))
+	for imageURL, result := range results {
+		verdicts[imageURL] = Evaluate(cfg, result)
+	}
+	return verdicts
+}
+
+// AnyFailed reports whether any verdict in the set failed policy evaluation.
+func AnyFailed(verdicts map[string]PolicyVerdict) bool {
+	for _, v := range verdicts {
+		if !v.Pass {
+			return true
+		}
+	}
+	return false
+}
+
+// isStale reports whether result's tag is older than maxAgeDays. This
+// measures tag/manifest age (result.StartTs, from quay.TagDetail.StartTs),
+// not when the tag was last scanned — the Quay tag endpoint doesn't expose a
+// last-scanned timestamp, so a long-lived tag that Clair re-scanned
+// yesterday is still "stale" here once it crosses maxAgeDays. StartTs is
+// zero for backends that don't thread through quay.TagDetail.StartTs (e.g.
+// OCIScanner), in which case there's nothing to compare and the scan is
+// treated as fresh rather than failed closed.
+func isStale(result quay.ImageScanResult, maxAgeDays int) bool {
+	if result.StartTs == 0 {
+		return false
+	}
+	maxAge := time.Duration(maxAgeDays) * 24 * time.Hour
+	return time.Since(time.Unix(result.StartTs, 0)) > maxAge
+}
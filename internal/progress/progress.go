@@ -0,0 +1,113 @@
+// internal/progress/progress.go
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"quay-scanner/internal/quay"
+)
+
+// Bar renders a single-line, carriage-return-updated progress indicator
+// while a multi-image scan runs: images completed/total, the image URLs
+// currently in flight, and running Critical/High finding counts.
+type Bar struct {
+	mu        sync.Mutex
+	out       io.Writer
+	total     int
+	completed int
+	critical  int
+	high      int
+	inFlight  map[string]bool
+	enabled   bool
+}
+
+// New creates a Bar for a run of total images, rendering to w when enabled
+// is true. Callers should compute enabled via ShouldRender.
+func New(w io.Writer, total int, enabled bool) *Bar {
+	return &Bar{out: w, total: total, inFlight: make(map[string]bool), enabled: enabled}
+}
+
+// ShouldRender reports whether a progress bar should be drawn: stderr must
+// be an interactive terminal, verbose logging must be off (its log lines
+// would corrupt the bar), and the caller mustn't have passed --no-progress.
+func ShouldRender(stderrFd uintptr, verbose bool, noProgress bool) bool {
+	if verbose || noProgress {
+		return false
+	}
+	return term.IsTerminal(int(stderrFd))
+}
+
+// Started marks imageURL as in flight and redraws the bar.
+func (b *Bar) Started(imageURL string) {
+	if !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inFlight[imageURL] = true
+	b.render()
+}
+
+// Finished marks imageURL as complete, folds its Critical/High findings
+// (including those of any manifest-list Children) into the running counts,
+// and redraws the bar.
+func (b *Bar) Finished(result quay.ImageScanResult) {
+	if !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.inFlight, result.ImageURL)
+	b.completed++
+	var tally func(r quay.ImageScanResult)
+	tally = func(r quay.ImageScanResult) {
+		if r.Report != nil {
+			for _, feature := range r.Report.Data.Layer.Features {
+				for _, vuln := range feature.Vulnerabilities {
+					switch vuln.Severity {
+					case "Critical":
+						b.critical++
+					case "High":
+						b.high++
+					}
+				}
+			}
+		}
+		for _, child := range r.Children {
+			if child != nil {
+				tally(*child)
+			}
+		}
+	}
+	tally(result)
+	b.render()
+}
+
+// Stop clears the progress line so whatever output follows (the results
+// table, JSON, NDJSON) starts on a clean line.
+func (b *Bar) Stop() {
+	if !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fmt.Fprint(b.out, "\r\033[K")
+}
+
+// render redraws the current line. Callers must hold b.mu.
+func (b *Bar) render() {
+	inFlight := make([]string, 0, len(b.inFlight))
+	for url := range b.inFlight {
+		inFlight = append(inFlight, url)
+	}
+	sort.Strings(inFlight)
+
+	fmt.Fprintf(b.out, "\rScanning %d/%d | Critical: %d  High: %d | in flight: %s\033[K",
+		b.completed, b.total, b.critical, b.high, strings.Join(inFlight, ", "))
+}
@@ -9,7 +9,9 @@ import (
 
 	// "net/http/httputil" // Keep if needed for debugging
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	// "os" // No longer needed here
 )
@@ -19,17 +21,57 @@ import (
 // const defaultTimeout = 15 * time.Second
 // const userAgent = "golang-quay-vuln-scanner/1.0"
 
-// Client manages communication with the Quay API.
+// Credentials bundles the ways a Client can authenticate to a Quay (or
+// Quay-compatible) API: a bearer token, an OAuth access token (sent the same
+// way over the wire as a bearer token), or HTTP Basic auth. At most one
+// should be set; BearerToken is tried first, then OAuthToken, then Basic.
+type Credentials struct {
+	BearerToken   string
+	OAuthToken    string
+	BasicAuthUser string
+	BasicAuthPass string
+}
+
+// RetryPolicy controls how Client.doRequest retries a failed request: up to
+// MaxAttempts tries, waiting InitialBackoff and multiplying by Multiplier
+// each time (capped at MaxBackoff), for any status in RetryOn or (if
+// RetryOnNetworkErrors) a network-level error. It's the quay package's own
+// plain copy of config.RetryConfig, built by main.go at startup, so this
+// package doesn't need to import config.
+type RetryPolicy struct {
+	MaxAttempts          int
+	InitialBackoff       time.Duration
+	MaxBackoff           time.Duration
+	Multiplier           float64
+	RetryOn              map[int]bool
+	RetryOnNetworkErrors bool
+}
+
+// maxAttempts normalizes MaxAttempts <= 0 to 1, i.e. no retries.
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Client manages communication with the Quay API. BaseURL, HTTPClient (for
+// its timeout), Creds, UserAgent, and Retry can change after construction
+// via UpdateConfig (a config.Watcher
+// hot-reload picks up new values on SIGHUP), so every other access to them
+// goes through mu to avoid racing with an in-flight request.
 type Client struct {
+	mu         sync.RWMutex
 	BaseURL    *url.URL
 	HTTPClient *http.Client
-	Token      string
+	Creds      Credentials
 	UserAgent  string // Add UserAgent field
+	Retry      RetryPolicy
 }
 
 // NewClient creates a new Quay API client using provided configuration.
 // baseURL, timeout, and userAgent should come from the loaded config.
-func NewClient(baseURL string, token string, timeout time.Duration, userAgent string) (*Client, error) {
+func NewClient(baseURL string, creds Credentials, timeout time.Duration, userAgent string, retry RetryPolicy) (*Client, error) {
 	// Validate inputs that MUST be provided
 	if baseURL == "" {
 		return nil, fmt.Errorf("quay API base URL cannot be empty")
@@ -60,33 +102,84 @@ func NewClient(baseURL string, token string, timeout time.Duration, userAgent st
 		HTTPClient: &http.Client{
 			Timeout: timeout, // Use the provided timeout
 		},
-		Token:     token,
+		Creds:     creds,
 		UserAgent: userAgent, // Store the provided UserAgent
+		Retry:     retry,
 	}, nil
 }
 
+// UpdateConfig atomically swaps the client's base URL, timeout, credentials,
+// user agent, and retry policy, taking effect for any request that hasn't
+// yet read them — in-flight requests already captured their own snapshot in
+// doRequest and are unaffected. This is what lets a config.Watcher reload
+// config.yaml on SIGHUP without restarting a scan in progress.
+func (c *Client) UpdateConfig(baseURL string, creds Credentials, timeout time.Duration, userAgent string, retry RetryPolicy) error {
+	if baseURL == "" {
+		return fmt.Errorf("quay API base URL cannot be empty")
+	}
+	parsedBaseURL, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL '%s': %w", baseURL, err)
+	}
+	if !strings.HasSuffix(parsedBaseURL.Path, "/") {
+		parsedBaseURL.Path += "/"
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if userAgent == "" {
+		userAgent = "golang-quay-scanner/unknown-version"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.BaseURL = parsedBaseURL
+	// Replace rather than mutate the http.Client, so a request already
+	// running on the old one keeps its timeout and doesn't race this write.
+	c.HTTPClient = &http.Client{Timeout: timeout}
+	c.Creds = creds
+	c.UserAgent = userAgent
+	c.Retry = retry
+	return nil
+}
+
+// snapshot returns a consistent copy of the fields UpdateConfig can change,
+// so doRequest can build and retry a request without holding c.mu for the
+// whole round trip.
+func (c *Client) snapshot() (baseURL *url.URL, creds Credentials, userAgent string, retry RetryPolicy) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.BaseURL, c.Creds, c.UserAgent, c.Retry
+}
+
 // doRequest performs an HTTP request and decodes the JSON response.
 func (c *Client) doRequest(method, path string, target interface{}) error {
+	baseURL, creds, userAgent, retry := c.snapshot()
+
 	relURL, err := url.Parse(path)
 	if err != nil {
 		return fmt.Errorf("invalid API path %q: %w", path, err)
 	}
-	fullURL := c.BaseURL.ResolveReference(relURL)
+	fullURL := baseURL.ResolveReference(relURL)
 
 	req, err := http.NewRequest(method, fullURL.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request for %s: %w", fullURL, err)
 	}
-	req.Header.Set("User-Agent", c.UserAgent) // Use the UserAgent from the client struct
+	req.Header.Set("User-Agent", userAgent)
 
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+	switch {
+	case creds.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+creds.BearerToken)
+	case creds.OAuthToken != "":
+		req.Header.Set("Authorization", "Bearer "+creds.OAuthToken)
+	case creds.BasicAuthUser != "":
+		req.SetBasicAuth(creds.BasicAuthUser, creds.BasicAuthPass)
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doWithRetry(req, fullURL.String(), retry)
 	if err != nil {
-		// Add URL to context for network errors
-		return fmt.Errorf("failed to execute request to %s: %w", fullURL, err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -106,21 +199,120 @@ func (c *Client) doRequest(method, path string, target interface{}) error {
 	return nil
 }
 
-// GetImageID fetches the image digest (SHA) for a given repo and tag.
-// ... (no changes needed in GetImageID itself) ...
-func (c *Client) GetImageID(repo, tag string) (string, error) {
-	// ... existing implementation ...
+// doWithRetry sends req, retrying per retry on network errors and on status
+// codes in retry.RetryOn, with exponential backoff. A 429 response carrying
+// a Retry-After header is honored in preference to the computed backoff.
+// req has no body (all Quay API calls are GETs), so it's safe to resend
+// as-is across attempts. The caller is responsible for closing the returned
+// response's body.
+func (c *Client) doWithRetry(req *http.Request, urlForLog string, retry RetryPolicy) (*http.Response, error) {
+	maxAttempts := retry.maxAttempts()
+	backoff := retry.InitialBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	maxBackoff := retry.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
+	multiplier := retry.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	// Capture the http.Client once, so every attempt of this request uses
+	// the timeout in effect when it started, even if UpdateConfig runs.
+	c.mu.RLock()
+	httpClient := c.HTTPClient
+	c.mu.RUnlock()
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to execute request to %s: %w", urlForLog, err)
+			if attempt == maxAttempts || !retry.RetryOnNetworkErrors {
+				return nil, lastErr
+			}
+			log.Printf("WARN: request to %s failed (attempt %d/%d), retrying in %v: %v", urlForLog, attempt, maxAttempts, backoff, err)
+			time.Sleep(backoff)
+			backoff = nextBackoff(backoff, multiplier, maxBackoff)
+			continue
+		}
+
+		if attempt == maxAttempts || !retry.RetryOn[resp.StatusCode] {
+			return resp, nil
+		}
+
+		wait := backoff
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+				wait = retryAfter
+			}
+		}
+		resp.Body.Close()
+		log.Printf("WARN: request to %s got status %d (attempt %d/%d), retrying in %v", urlForLog, resp.StatusCode, attempt, maxAttempts, wait)
+		time.Sleep(wait)
+		backoff = nextBackoff(backoff, multiplier, maxBackoff)
+	}
+	return nil, lastErr
+}
+
+// nextBackoff computes the next exponential backoff duration, capped at max.
+func nextBackoff(current time.Duration, multiplier float64, max time.Duration) time.Duration {
+	next := time.Duration(float64(current) * multiplier)
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+// parseRetryAfter parses an HTTP Retry-After header, which per RFC 7231 is
+// either a number of delta-seconds or an HTTP-date. Returns 0 if header is
+// empty or unparseable as either form.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
+
+// GetTagDetail fetches the full tag metadata for a given repo and tag,
+// including whether it resolves to a manifest list (IsManifestList).
+func (c *Client) GetTagDetail(repo, tag string) (*TagDetail, error) {
 	path := fmt.Sprintf("repository/%s/tag/%s", repo, url.PathEscape(tag))
 	var tagDetail TagDetail
 	err := c.doRequest("GET", path, &tagDetail)
 	if err != nil {
 		// Check for 404 specifically, might indicate tag not found
 		if strings.Contains(err.Error(), "status 404") {
-			return "", fmt.Errorf("tag '%s' not found in repository '%s' (or repository is private/inaccessible)", tag, repo)
+			return nil, fmt.Errorf("tag '%s' not found in repository '%s' (or repository is private/inaccessible)", tag, repo)
 		}
-		return "", fmt.Errorf("failed to get tag details for %s:%s: %w", repo, tag, err)
+		return nil, fmt.Errorf("failed to get tag details for %s:%s: %w", repo, tag, err)
 	}
+	return &tagDetail, nil
+}
 
+// GetImageID fetches the image digest (SHA) for a given repo and tag.
+func (c *Client) GetImageID(repo, tag string) (string, error) {
+	tagDetail, err := c.GetTagDetail(repo, tag)
+	if err != nil {
+		return "", err
+	}
+	return ImageIDFromTagDetail(tagDetail, tag)
+}
+
+// ImageIDFromTagDetail extracts the image digest (SHA) from a TagDetail
+// already fetched via GetTagDetail, so a caller that needs both the tag
+// detail (e.g. to check IsManifestList) and the digest doesn't have to fetch
+// the tag endpoint twice.
+func ImageIDFromTagDetail(tagDetail *TagDetail, tag string) (string, error) {
 	if tagDetail.ManifestDigest != "" {
 		digest := strings.TrimPrefix(tagDetail.ManifestDigest, "sha256:")
 		return digest, nil
@@ -132,6 +324,52 @@ func (c *Client) GetImageID(repo, tag string) (string, error) {
 	return "", fmt.Errorf("could not determine image digest for tag '%s' (no manifest_digest or docker_image_id found)", tag)
 }
 
+// manifestListDoc is the subset of an OCI/Docker manifest list this tool
+// needs: one digest per platform.
+type manifestListDoc struct {
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant,omitempty"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// manifestResponse wraps Quay's manifest endpoint, which returns the raw
+// manifest document as a JSON-encoded string field.
+type manifestResponse struct {
+	ManifestData string `json:"manifest_data"`
+}
+
+// GetManifestChildren fetches the per-platform child digests of a
+// manifest-list image, keyed by "os/arch" (or "os/arch/variant" when a
+// variant is present, e.g. "linux/arm/v7").
+func (c *Client) GetManifestChildren(repo, digest string) (map[string]string, error) {
+	path := fmt.Sprintf("repository/%s/manifest/sha256:%s", repo, digest)
+	var resp manifestResponse
+	err := c.doRequest("GET", path, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest for %s@sha256:%s: %w", repo, digest, err)
+	}
+
+	var doc manifestListDoc
+	if err := json.Unmarshal([]byte(resp.ManifestData), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest list for %s@sha256:%s: %w", repo, digest, err)
+	}
+
+	children := make(map[string]string, len(doc.Manifests))
+	for _, m := range doc.Manifests {
+		platform := m.Platform.OS + "/" + m.Platform.Architecture
+		if m.Platform.Variant != "" {
+			platform += "/" + m.Platform.Variant
+		}
+		children[platform] = strings.TrimPrefix(m.Digest, "sha256:")
+	}
+	return children, nil
+}
+
 // GetVulnerabilities fetches the security report for a given repo and image digest.
 // ... (no changes needed in GetVulnerabilities itself) ...
 func (c *Client) GetVulnerabilities(repo, imageDigest string) (*SecurityReport, error) {
@@ -68,4 +68,13 @@ type ImageScanResult struct {
 	ImageURL string          `json:"imageUrl"`
 	Report   *SecurityReport `json:"report,omitempty"` // Pointer, nil if error or not scanned
 	Error    string          `json:"error,omitempty"`  // Store error as string for easy JSON marshalling
+	// StartTs is the tag's TagDetail.StartTs (epoch seconds the tag/manifest
+	// was created), when the backend that produced this result is a
+	// ManifestInspector. Zero for backends that don't expose it, in which
+	// case policy's max_age_days gate treats the scan as fresh.
+	StartTs int64 `json:"startTs,omitempty"`
+	// Children holds per-platform scan results when ImageURL resolved to a
+	// manifest list, keyed by platform (e.g. "linux/amd64"). Nil for
+	// single-platform images.
+	Children map[string]*ImageScanResult `json:"children,omitempty"`
 }
@@ -0,0 +1,121 @@
+// internal/report/report.go
+package report
+
+import (
+	"sort"
+
+	"quay-scanner/internal/quay"
+)
+
+// SeverityCounts tallies vulnerabilities by severity across a set of images.
+type SeverityCounts struct {
+	Critical   int `json:"critical"`
+	High       int `json:"high"`
+	Medium     int `json:"medium"`
+	Low        int `json:"low"`
+	Negligible int `json:"negligible"`
+	Unknown    int `json:"unknown"`
+}
+
+// PackageCount is one entry in Summary's top-N most-affected packages list.
+type PackageCount struct {
+	Package string `json:"package"`
+	Count   int    `json:"count"`
+}
+
+// Summary aggregates vulnerability data across every scanned image. It's
+// the one shared shape consumed by the human/JSON formatters, the webhook
+// notifier's run_summary event, and (eventually) the policy engine, so all
+// three surfaces agree on the same numbers.
+type Summary struct {
+	ImagesScanned   int            `json:"imagesScanned"`
+	ImagesFailed    int            `json:"imagesFailed"`
+	UniqueCVEs      int            `json:"uniqueCves"`
+	SeverityCounts  SeverityCounts `json:"severityCounts"`
+	TopPackages     []PackageCount `json:"topPackages,omitempty"`
+	FixabilityRatio float64        `json:"fixabilityRatio"`
+}
+
+// Summarize builds a Summary over results, including any manifest-list
+// Children nested under an image. topN bounds how many entries appear in
+// TopPackages; 0 means no limit.
+func Summarize(results map[string]quay.ImageScanResult, topN int) Summary {
+	summary := Summary{ImagesScanned: len(results)}
+
+	cves := make(map[string]bool)
+	packageCounts := make(map[string]int)
+	var totalVulns, fixableVulns int
+
+	var walk func(r quay.ImageScanResult)
+	walk = func(r quay.ImageScanResult) {
+		if r.Report != nil {
+			for _, feature := range r.Report.Data.Layer.Features {
+				for _, vuln := range feature.Vulnerabilities {
+					cves[vuln.Name] = true
+					packageCounts[feature.Name]++
+					totalVulns++
+					if vuln.FixedBy != "" {
+						fixableVulns++
+					}
+					tallySeverity(&summary.SeverityCounts, vuln.Severity)
+				}
+			}
+		}
+		for _, child := range r.Children {
+			if child != nil {
+				walk(*child)
+			}
+		}
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			summary.ImagesFailed++
+		}
+		walk(r)
+	}
+
+	summary.UniqueCVEs = len(cves)
+	if totalVulns > 0 {
+		summary.FixabilityRatio = float64(fixableVulns) / float64(totalVulns)
+	}
+	summary.TopPackages = topPackages(packageCounts, topN)
+
+	return summary
+}
+
+func tallySeverity(counts *SeverityCounts, severity string) {
+	switch severity {
+	case "Critical":
+		counts.Critical++
+	case "High":
+		counts.High++
+	case "Medium":
+		counts.Medium++
+	case "Low":
+		counts.Low++
+	case "Negligible":
+		counts.Negligible++
+	default:
+		counts.Unknown++
+	}
+}
+
+// topPackages returns the topN packages by vulnerability count, descending,
+// breaking ties alphabetically for stable output.
+func topPackages(counts map[string]int, topN int) []PackageCount {
+	list := make([]PackageCount, 0, len(counts))
+	for pkg, count := range counts {
+		list = append(list, PackageCount{Package: pkg, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Package < list[j].Package
+	})
+	if topN > 0 && len(list) > topN {
+		list = list[:topN]
+	}
+	return list
+}
@@ -0,0 +1,258 @@
+// internal/scanner/oci.go
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"quay-scanner/internal/quay"
+)
+
+// ReportBackendKind selects which vulnerability data source an OCIScanner
+// queries once it has resolved an image digest.
+type ReportBackendKind string
+
+const (
+	// ReportBackendClairV4 talks to a standalone Clair v4 deployment, the
+	// same backend Quay itself runs behind the scenes.
+	ReportBackendClairV4 ReportBackendKind = "clairv4"
+	// ReportBackendTrivyServer talks to a `trivy server` REST endpoint.
+	ReportBackendTrivyServer ReportBackendKind = "trivy-server"
+)
+
+// OCIScanner resolves image digests against any OCI Distribution v2
+// registry, then fetches vulnerability data from a separate Clair v4 or
+// Trivy-server endpoint. This is how `containers/image` separates registry
+// transport from vulnerability scanning, and it's what lets the tool scan
+// registry.redhat.io, ghcr.io, or a self-hosted Harbor through the same
+// worker pool used for quay.io.
+type OCIScanner struct {
+	RegistryBaseURL string // e.g. "https://ghcr.io"
+	ReportBaseURL   string // e.g. "https://clair.internal:8080"
+	ReportBackend   ReportBackendKind
+	Token           string // bearer token for the registry, if required
+	HTTPClient      *http.Client
+}
+
+// manifestAcceptHeaders covers both OCI and legacy Docker manifest media
+// types so digest resolution works against registries of either vintage.
+const manifestAcceptHeaders = "application/vnd.oci.image.manifest.v1+json, application/vnd.oci.image.index.v1+json, application/vnd.docker.distribution.manifest.v2+json, application/vnd.docker.distribution.manifest.list.v2+json"
+
+// NewOCIScanner creates an OCIScanner backed by registryBaseURL for digest
+// resolution and reportBaseURL for vulnerability data.
+func NewOCIScanner(registryBaseURL, reportBaseURL string, backend ReportBackendKind, token string, timeout time.Duration) (*OCIScanner, error) {
+	if registryBaseURL == "" {
+		return nil, fmt.Errorf("OCI registry base URL cannot be empty")
+	}
+	if reportBaseURL == "" {
+		return nil, fmt.Errorf("vulnerability report backend base URL cannot be empty")
+	}
+	switch backend {
+	case ReportBackendClairV4, ReportBackendTrivyServer:
+	default:
+		return nil, fmt.Errorf("unknown report backend %q", backend)
+	}
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
+	return &OCIScanner{
+		RegistryBaseURL: strings.TrimSuffix(registryBaseURL, "/"),
+		ReportBaseURL:   strings.TrimSuffix(reportBaseURL, "/"),
+		ReportBackend:   backend,
+		Token:           token,
+		HTTPClient:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// GetImageID resolves repo:tag to the manifest digest reported by the
+// registry's Docker-Content-Digest header, per the OCI Distribution spec.
+func (s *OCIScanner) GetImageID(repo, tag string) (string, error) {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", s.RegistryBaseURL, repo, url.PathEscape(tag))
+
+	req, err := http.NewRequest(http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("building manifest request for %s:%s: %w", repo, tag, err)
+	}
+	req.Header.Set("Accept", manifestAcceptHeaders)
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching manifest for %s:%s: %w", repo, tag, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("manifest request for %s:%s failed with status %s", repo, tag, resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry response for %s:%s did not include a Docker-Content-Digest header", repo, tag)
+	}
+	return strings.TrimPrefix(digest, "sha256:"), nil
+}
+
+// GetVulnerabilities fetches the security report for imageDigest from the
+// configured report backend and translates it into quay.SecurityReport so
+// the rest of the tool (formatters, policy engine, notifier) stays backend
+// agnostic.
+func (s *OCIScanner) GetVulnerabilities(repo, imageDigest string) (*quay.SecurityReport, error) {
+	switch s.ReportBackend {
+	case ReportBackendClairV4:
+		return s.getClairV4Report(repo, imageDigest)
+	case ReportBackendTrivyServer:
+		return s.getTrivyReport(repo, imageDigest)
+	default:
+		return nil, fmt.Errorf("unknown report backend %q", s.ReportBackend)
+	}
+}
+
+// clairV4IndexReport is the subset of Clair v4's VulnerabilityReport
+// (https://quay.github.io/clair/reference/api.html) this tool cares about.
+type clairV4IndexReport struct {
+	Vulnerabilities map[string]struct {
+		Name           string `json:"name"`
+		Description    string `json:"description"`
+		Links          string `json:"links"`
+		Severity       string `json:"normalized_severity"`
+		FixedInVersion string `json:"fixed_in_version"`
+		Package        struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"package"`
+	} `json:"vulnerabilities"`
+}
+
+func (s *OCIScanner) getClairV4Report(repo, imageDigest string) (*quay.SecurityReport, error) {
+	reportURL := fmt.Sprintf("%s/api/v1/vulnerability_report/sha256:%s", s.ReportBaseURL, imageDigest)
+
+	var clairReport clairV4IndexReport
+	if err := s.fetchJSON(reportURL, &clairReport); err != nil {
+		return nil, fmt.Errorf("fetching Clair v4 report for %s@sha256:%s: %w", repo, imageDigest, err)
+	}
+
+	features := make([]quay.Feature, 0, len(clairReport.Vulnerabilities))
+	for _, v := range clairReport.Vulnerabilities {
+		features = append(features, quay.Feature{
+			Name:    v.Package.Name,
+			Version: v.Package.Version,
+			Vulnerabilities: []quay.Vulnerability{{
+				Name:        v.Name,
+				Description: v.Description,
+				Link:        v.Links,
+				Severity:    v.Severity,
+				FixedBy:     v.FixedInVersion,
+			}},
+		})
+	}
+
+	return &quay.SecurityReport{
+		Status: "scanned",
+		Data: quay.SecurityData{
+			Layer: quay.Layer{
+				Name:     imageDigest,
+				Features: features,
+			},
+		},
+	}, nil
+}
+
+// trivyServerReport is the subset of `trivy server`'s client-mode JSON
+// response this tool cares about.
+type trivyServerReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Description      string `json:"Description"`
+			PrimaryURL       string `json:"PrimaryURL"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func (s *OCIScanner) getTrivyReport(repo, imageDigest string) (*quay.SecurityReport, error) {
+	reportURL := fmt.Sprintf("%s/scan?image=%s@sha256:%s", s.ReportBaseURL, url.QueryEscape(repo), imageDigest)
+
+	var trivyReport trivyServerReport
+	if err := s.fetchJSON(reportURL, &trivyReport); err != nil {
+		return nil, fmt.Errorf("fetching Trivy server report for %s@sha256:%s: %w", repo, imageDigest, err)
+	}
+
+	var features []quay.Feature
+	for _, result := range trivyReport.Results {
+		for _, v := range result.Vulnerabilities {
+			features = append(features, quay.Feature{
+				Name:    v.PkgName,
+				Version: v.InstalledVersion,
+				Vulnerabilities: []quay.Vulnerability{{
+					Name:        v.VulnerabilityID,
+					Description: v.Description,
+					Link:        v.PrimaryURL,
+					Severity:    normalizeTrivySeverity(v.Severity),
+					FixedBy:     v.FixedVersion,
+				}},
+			})
+		}
+	}
+
+	return &quay.SecurityReport{
+		Status: "scanned",
+		Data: quay.SecurityData{
+			Layer: quay.Layer{
+				Name:     imageDigest,
+				Features: features,
+			},
+		},
+	}, nil
+}
+
+// normalizeTrivySeverity maps Trivy's all-caps severities (UNKNOWN, LOW,
+// MEDIUM, HIGH, CRITICAL) to the capitalized form the rest of the tool keys
+// on (policy's severityRank, report's tallySeverity, the progress bar),
+// which follows Clair v4's convention. Anything Trivy didn't report as one
+// of its known severities falls back to "Unknown" rather than propagating a
+// string nothing downstream recognizes.
+func normalizeTrivySeverity(severity string) string {
+	switch strings.ToUpper(severity) {
+	case "LOW":
+		return "Low"
+	case "MEDIUM":
+		return "Medium"
+	case "HIGH":
+		return "High"
+	case "CRITICAL":
+		return "Critical"
+	default:
+		return "Unknown"
+	}
+}
+
+// fetchJSON is a small shared helper for the two report backends; it doesn't
+// need the Quay client's retry/auth semantics since report backends here are
+// assumed to be internal, unauthenticated services.
+func (s *OCIScanner) fetchJSON(reportURL string, target interface{}) error {
+	resp, err := s.HTTPClient.Get(reportURL)
+	if err != nil {
+		return fmt.Errorf("executing request to %s: %w", reportURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %s", reportURL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("decoding response from %s: %w", reportURL, err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+// internal/scanner/quay_scanner.go
+package scanner
+
+import "quay-scanner/internal/quay"
+
+// quay.Client already implements Scanner (GetImageID, GetVulnerabilities)
+// and ManifestInspector (GetTagDetail, GetManifestChildren), so quay.io
+// keeps being driven by the original client with no adapter code.
+var _ Scanner = (*quay.Client)(nil)
+var _ ManifestInspector = (*quay.Client)(nil)
@@ -0,0 +1,61 @@
+// internal/scanner/scanner.go
+package scanner
+
+import "quay-scanner/internal/quay"
+
+// Scanner abstracts a vulnerability-scanning backend. quay.Client already
+// satisfies it, so scanning quay.io keeps working unchanged; other
+// implementations can talk to a standalone Clair v4 deployment or a
+// Trivy-server instance fronting any OCI Distribution v2 registry.
+type Scanner interface {
+	// GetImageID resolves a repo:tag reference to the digest that identifies
+	// the scanned image.
+	GetImageID(repo, tag string) (string, error)
+	// GetVulnerabilities fetches the security report for a previously
+	// resolved image digest.
+	GetVulnerabilities(repo, imageID string) (*quay.SecurityReport, error)
+}
+
+// ManifestInspector is implemented by Scanner backends that can resolve a
+// tag to its manifest-list status and, for manifest lists, its per-platform
+// child digests (Quay's API does both). Backends without multi-arch support
+// simply don't implement it, and callers type-assert for it before using it.
+type ManifestInspector interface {
+	GetTagDetail(repo, tag string) (*quay.TagDetail, error)
+	GetManifestChildren(repo, digest string) (map[string]string, error)
+}
+
+// Registry dispatches to a Scanner by registry hostname (e.g. "quay.io",
+// "ghcr.io"), so a single worker pool can drive several backends in one run.
+type Registry struct {
+	scanners map[string]Scanner
+	fallback Scanner
+}
+
+// NewRegistry builds a Registry from a host->Scanner map. fallback (may be
+// nil) is used for hosts with no explicit entry.
+func NewRegistry(scanners map[string]Scanner, fallback Scanner) *Registry {
+	return &Registry{scanners: scanners, fallback: fallback}
+}
+
+// For returns the Scanner registered for host, or the fallback if none is
+// registered. It returns an error only when neither is available.
+func (r *Registry) For(host string) (Scanner, error) {
+	if s, ok := r.scanners[host]; ok {
+		return s, nil
+	}
+	if r.fallback != nil {
+		return r.fallback, nil
+	}
+	return nil, &UnconfiguredRegistryError{Host: host}
+}
+
+// UnconfiguredRegistryError is returned by Registry.For when a host has no
+// registered Scanner and no fallback was configured.
+type UnconfiguredRegistryError struct {
+	Host string
+}
+
+func (e *UnconfiguredRegistryError) Error() string {
+	return "no scanner backend configured for registry '" + e.Host + "'"
+}